@@ -0,0 +1,82 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuthManagerVerifyRoundTrip(t *testing.T) {
+	a := NewAuthManager("s3cret")
+	tok, err := a.Mint(JoinToken{Room: "room1", User: "alice", ValidUntil: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	got, err := a.Verify(tok)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.Room != "room1" || got.User != "alice" {
+		t.Fatalf("Verify returned %+v", got)
+	}
+}
+
+func TestAuthManagerVerifyRejectsBadSignature(t *testing.T) {
+	a := NewAuthManager("s3cret")
+	tok, err := a.Mint(JoinToken{Room: "room1", User: "alice", ValidUntil: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	other := NewAuthManager("different")
+	if _, err := other.Verify(tok); err == nil {
+		t.Fatal("Verify succeeded with the wrong secret")
+	}
+}
+
+func TestAuthManagerVerifyRejectsExpired(t *testing.T) {
+	a := NewAuthManager("s3cret")
+	tok, err := a.Mint(JoinToken{Room: "room1", User: "alice", ValidUntil: time.Now().Add(-time.Minute).Unix()})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if _, err := a.Verify(tok); err == nil {
+		t.Fatal("Verify succeeded with an expired token")
+	}
+}
+
+// A zero/missing ValidUntil must be treated as already-expired, not as
+// "never expires" -- a forgotten field must not mint a permanently valid
+// token.
+func TestAuthManagerVerifyRejectsZeroValidUntil(t *testing.T) {
+	a := NewAuthManager("s3cret")
+	tok, err := a.Mint(JoinToken{Room: "room1", User: "alice"})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if _, err := a.Verify(tok); err == nil {
+		t.Fatal("Verify succeeded with ValidUntil unset")
+	}
+}
+
+func TestAuthManagerSetSecretRotatesAtomically(t *testing.T) {
+	a := NewAuthManager("old")
+	tok, err := a.Mint(JoinToken{Room: "room1", User: "alice", ValidUntil: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	a.SetSecret("new")
+	if _, err := a.Verify(tok); err == nil {
+		t.Fatal("Verify succeeded against a token signed with the rotated-out secret")
+	}
+	tok2, err := a.Mint(JoinToken{Room: "room1", User: "alice", ValidUntil: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if _, err := a.Verify(tok2); err != nil {
+		t.Fatalf("Verify failed against a token signed with the current secret: %v", err)
+	}
+}
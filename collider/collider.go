@@ -17,6 +17,7 @@ import (
 	"io/ioutil"
 	"log"
 	"math/rand"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -25,9 +26,16 @@ import (
 
 const registerTimeoutSec = 60 * 10
 
-// This is a temporary solution to avoid holding a zombie connection forever, by
-// setting a 1 day timeout on reading from the WebSocket connection.
-const wsReadTimeoutSec = 60 * 60 * 24
+// Ping/pong keepalive tuning for registered WebSocket connections. The read
+// deadline is refreshed to now+pongWait on every frame received (including
+// a pong), so a connection whose peer stops responding is detected and
+// closed within pongWait instead of the 1-day read deadline this used to
+// carry.
+const (
+	wsPingPeriod = 54 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsWriteWait  = 10 * time.Second
+)
 
 const RESPONSE_ERROR = "ERROR"
 const RESPONSE_ROOM_FULL = "FULL"
@@ -63,22 +71,121 @@ func generateRandom(n int, runes string) string {
 	return string(b)
 }
 
+// defaultMaxParticipantsPerRoom preserves today's 1:1 call behavior.
+const defaultMaxParticipantsPerRoom = 2
+
+// defaultMaxQueuedMessages bounds how many offer/candidate messages can
+// pile up for a client whose peer never joins or stops draining its
+// queue, so that path can no longer grow without limit.
+const defaultMaxQueuedMessages = 64
+
 type Collider struct {
-	*roomTable
-	dash *dashboard
+	store                  RoomStore
+	roomSrvUrl             string
+	dash                   *dashboard
+	maxParticipantsPerRoom int
+	auth                   *AuthManager
+	backend                *BackendNotifier
+	maxQueuedMessages      int
+	disconnectOnOverflow   bool
+	grpcPort               int
+}
+
+// Option configures optional Collider behavior, such as which RoomStore
+// backs it, following the same pattern as other constructors in this repo.
+type Option func(*Collider)
+
+// WithRoomStore overrides the default in-memory RoomStore, e.g. with a
+// clustered implementation so multiple collider instances can share rooms.
+func WithRoomStore(store RoomStore) Option {
+	return func(c *Collider) {
+		c.store = store
+	}
+}
+
+// WithMaxParticipantsPerRoom raises rooms beyond the default 1:1 call so
+// N-party WebRTC meshes can be built on top of this signaler.
+func WithMaxParticipantsPerRoom(n int) Option {
+	return func(c *Collider) {
+		c.maxParticipantsPerRoom = n
+	}
+}
+
+// WithAuth requires every join (HTTP and WebSocket register) to carry a
+// valid JoinToken signed by auth, so an application server controls who
+// may enter a room instead of anyone who guesses the room ID.
+func WithAuth(auth *AuthManager) Option {
+	return func(c *Collider) {
+		c.auth = auth
+	}
+}
+
+// WithBackendNotifier reports room lifecycle events (join, leave, timeout,
+// room_empty) to an application server, and lets it veto a join by
+// responding with a non-2xx status.
+func WithBackendNotifier(backend *BackendNotifier) Option {
+	return func(c *Collider) {
+		c.backend = backend
+	}
+}
+
+// WithMaxQueuedMessages bounds how many messages can be queued for a
+// client awaiting its peer; 0 disables the bound. See
+// WithDisconnectOnQueueOverflow for what happens once it's reached.
+func WithMaxQueuedMessages(n int) Option {
+	return func(c *Collider) {
+		c.maxQueuedMessages = n
+	}
+}
+
+// WithDisconnectOnQueueOverflow switches the queue-overflow policy from
+// the default (drop the oldest queued message) to disconnecting the
+// client whose queue is full, for deployments that would rather shed a
+// slow consumer than silently lose its messages.
+func WithDisconnectOnQueueOverflow() Option {
+	return func(c *Collider) {
+		c.disconnectOnOverflow = true
+	}
+}
+
+// WithGRPCPort starts the gRPC signaling frontend (see grpc.go) on
+// grpcPort alongside the HTTP/WebSocket listener when Run is called. 0
+// (the default) leaves it disabled.
+func WithGRPCPort(grpcPort int) Option {
+	return func(c *Collider) {
+		c.grpcPort = grpcPort
+	}
 }
 
 func NewCollider(rs string) *Collider {
 	return &Collider{
-		roomTable: newRoomTable(time.Second*registerTimeoutSec, rs),
-		dash:      newDashboard(),
+		store:                  newRoomTable(time.Second*registerTimeoutSec, rs),
+		roomSrvUrl:             rs,
+		dash:                   newDashboard(),
+		maxParticipantsPerRoom: defaultMaxParticipantsPerRoom,
+		maxQueuedMessages:      defaultMaxQueuedMessages,
 	}
 }
 
-// Run starts the collider server and blocks the thread until the program exits.
-func (c *Collider) Run(p int, useTls bool) {
+// Run starts the collider server and blocks the thread until the program
+// exits. Options are applied before the server starts listening, e.g. to
+// swap in a clustered RoomStore via WithRoomStore.
+func (c *Collider) Run(p int, useTls bool, opts ...Option) {
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.grpcPort != 0 {
+		go func() {
+			if err := c.listenAndServeGRPC(c.grpcPort); err != nil {
+				log.Fatal("Run: listenAndServeGRPC: " + err.Error())
+			}
+		}()
+	}
+
 	http.Handle("/ws", websocket.Handler(c.wsHandler))
 	http.HandleFunc("/status", c.httpStatusHandler)
+	http.Handle("/metrics", c.dash.Handler())
 	http.HandleFunc("/", c.httpHandler)
 
 	var e error
@@ -101,7 +208,17 @@ func (c *Collider) Run(p int, useTls bool) {
 		}
 		server := &http.Server{Addr: pstr, Handler: nil, TLSConfig: config}
 
-		e = server.ListenAndServeTLS("/cert/cert.pem", "/cert/key.pem")
+		var lis net.Listener
+		lis, e = net.Listen("tcp", pstr)
+		if e == nil {
+			cert, certErr := tls.LoadX509KeyPair("/cert/cert.pem", "/cert/key.pem")
+			if certErr != nil {
+				e = certErr
+			} else {
+				config.Certificates = []tls.Certificate{cert}
+				e = server.Serve(&handshakeCountingListener{Listener: tls.NewListener(lis, config), dash: c.dash})
+			}
+		}
 	} else {
 		e = http.ListenAndServe(pstr, nil)
 	}
@@ -111,92 +228,101 @@ func (c *Collider) Run(p int, useTls bool) {
 	}
 }
 
-func (c *Collider) addClientToRoom(room_id, client_id string, is_loopback bool) (is_initiator bool, messages []string, err error) {
-	room := c.roomTable.room(room_id)
-	occupancy := len(room.clients)
-	if occupancy >= 2 {
-		err = errors.New(RESPONSE_ROOM_FULL)
-	} else if _, ok := room.clients[client_id]; ok {
+func (c *Collider) addClientToRoom(room_id, client_id string, is_loopback bool) (is_initiator bool, peers []string, messages []string, err error) {
+	if c.store.clientExists(room_id, client_id) {
 		err = errors.New(RESPONSE_DUPLICATE_CLIENT)
-	} else if occupancy == 0 {
-		is_initiator = true
-		room.client(client_id)
-		if is_loopback {
-			room.client(LOOPBACK_CLIENT_ID)
+		return
+	}
+	occupancy := c.store.occupancy(room_id)
+	if occupancy >= c.maxParticipantsPerRoom {
+		err = errors.New(RESPONSE_ROOM_FULL)
+		return
+	}
+	if c.backend != nil {
+		if notifyErr := c.backend.NotifyJoin(room_id, client_id); notifyErr != nil {
+			fmt.Printf("Backend vetoed join for room %s client %s: %v\n", room_id, client_id, notifyErr)
+			err = errors.New(RESPONSE_ERROR)
+			return
 		}
-	} else {
-		is_initiator = false
-		other_client, _ := room.other_client(client_id)
-		messages = other_client.msgs
-		room.client(client_id)
-		other_client.msgs = nil
+	}
+	is_initiator = occupancy == 0
+	if is_initiator {
+		c.dash.onRoomCreated()
+	}
+	peers = c.store.addClient(room_id, client_id)
+	if is_initiator && is_loopback {
+		c.store.addClient(room_id, LOOPBACK_CLIENT_ID)
+	}
+	for _, peer_id := range peers {
+		messages = append(messages, c.store.drainMessages(room_id, peer_id)...)
 	}
 	return
 }
 
 func (c *Collider) removeClientFromRoom(room_id, client_id string) error {
-	if _, ok := c.roomTable.rooms[room_id]; !ok {
+	if !c.store.roomExists(room_id) {
 		fmt.Printf("Unknown room: %s\n", room_id)
 		return errors.New(RESPONSE_UNKNOWN_ROOM)
 	}
-	room := c.roomTable.room(room_id)
-	if _, ok := room.clients[client_id]; !ok {
+	if !c.store.clientExists(room_id, client_id) {
 		fmt.Printf("Unknown client: %s\n", client_id)
 		return errors.New(RESPONSE_UNKNOWN_CLIENT)
 	}
-	room.remove(client_id)
-	if _, ok := room.clients[LOOPBACK_CLIENT_ID]; ok {
-		room.remove(LOOPBACK_CLIENT_ID)
+	c.store.removeClient(room_id, client_id)
+	if c.store.clientExists(room_id, LOOPBACK_CLIENT_ID) {
+		c.store.removeClient(room_id, LOOPBACK_CLIENT_ID)
+	}
+	if c.backend != nil {
+		c.backend.Notify(backendEventLeave, room_id, client_id)
 	}
-	if len(room.clients) > 0 {
-		if client, err := room.other_client(client_id); err == nil {
-			client.is_initiator = true
+	if !c.store.roomExists(room_id) {
+		c.dash.onRoomClosed()
+		if c.backend != nil {
+			c.backend.Notify(backendEventRoomEmpty, room_id, "")
 		}
-	} else {
-		delete(c.roomTable.rooms, room_id)
 	}
 	return nil
 }
 
 func (c *Collider) saveMessageFromClient(room_id, client_id string, message string) (saved bool, err error) {
-	if _, ok := c.roomTable.rooms[room_id]; !ok {
+	if !c.store.roomExists(room_id) {
 		fmt.Printf("Unknown room: %s\n", room_id)
 		err = errors.New(RESPONSE_UNKNOWN_ROOM)
 		return
 	}
-	room := c.roomTable.room(room_id)
-	client, ok := room.clients[client_id]
-	if !ok {
+	if !c.store.clientExists(room_id, client_id) {
 		fmt.Printf("Unknown client: %s\n", client_id)
 		err = errors.New(RESPONSE_UNKNOWN_CLIENT)
 		return
 	}
-	if len(room.clients) > 1 {
+	if c.store.occupancy(room_id) > 1 {
 		return
 	}
 
-	client.enqueue(message)
-	saved = true
-	fmt.Printf("Saved message for room  %s client %s with message %s, total saved msg count %d\n", room_id, client_id, message, len(client.msgs))
+	if c.maxQueuedMessages > 0 && c.store.queueDepth(room_id, client_id) >= c.maxQueuedMessages {
+		if c.disconnectOnOverflow {
+			fmt.Printf("Queue overflow for room %s client %s: disconnecting\n", room_id, client_id)
+			c.store.disconnect(room_id, client_id)
+			err = errors.New(RESPONSE_ERROR)
+			return
+		}
+		c.store.dropOldest(room_id, client_id)
+	}
 
-	return
-}
+	saved = c.store.enqueue(room_id, client_id, message)
+	c.dash.observeQueueDepth(c.store.queueDepth(room_id, client_id))
+	fmt.Printf("Saved message for room  %s client %s with message %s\n", room_id, client_id, message)
 
-func (c *Collider) sendMessageToCollider(w http.ResponseWriter, room_id, client_id string, message string) {
-	fmt.Printf("Forwarding message to collider for room  %s client %s with message %s\n", room_id, client_id, message)
-	if err := c.roomTable.send(room_id, client_id, message); err != nil {
-		c.httpError("Failed to send the message: "+err.Error(), w)
-		return
-	}
-	c.writeMessageResponse(w, RESPONSE_SUCCESS)
+	return
 }
 
 // Returns appropriate room parameters based on query parameters in the request.
-func (c *Collider) getRoomParameters(room_id, client_id string, is_initiator bool) map[string]interface{} {
+func (c *Collider) getRoomParameters(room_id, client_id string, is_initiator bool, peers []string) map[string]interface{} {
 	params := make(map[string]interface{})
 	params["room_id"] = room_id
 	params["client_id"] = client_id
 	params["is_initiator"] = is_initiator
+	params["peers"] = peers
 	params["wss_url"] = "ws://" + c.roomSrvUrl + ":443/ws"
 	params["wss_post_url"] = "http://" + c.roomSrvUrl + ":443"
 	return params
@@ -236,7 +362,7 @@ func (c *Collider) httpStatusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Access-Control-Allow-Origin", "*")
 	w.Header().Add("Access-Control-Allow-Methods", "GET")
 
-	rp := c.dash.getReport(c.roomTable)
+	rp := c.dash.getReport()
 	enc := json.NewEncoder(w)
 	if err := enc.Encode(rp); err != nil {
 		err = errors.New("Failed to encode to JSON: err=" + err.Error())
@@ -263,16 +389,38 @@ func (c *Collider) httpJoinHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		room_id = p[2]
 	}
-	client_id := generateRandom(8, runesDigital)
-	is_initiator, messages, err := c.addClientToRoom(room_id, client_id, is_loopback)
+
+	var client_id string
+	if c.auth != nil {
+		tok, err := c.auth.Verify(r.URL.Query().Get("token"))
+		if err != nil {
+			fmt.Printf("Rejected join for room %s: %v\n", room_id, err)
+			c.dash.onJoinResult(RESPONSE_INVALID_REQUEST)
+			c.writeJoinResponse(w, RESPONSE_INVALID_REQUEST, make(map[string]interface{}), nil)
+			return
+		}
+		if tok.Room != room_id {
+			fmt.Printf("Rejected join for room %s: token is for room %s\n", room_id, tok.Room)
+			c.dash.onJoinResult(RESPONSE_INVALID_REQUEST)
+			c.writeJoinResponse(w, RESPONSE_INVALID_REQUEST, make(map[string]interface{}), nil)
+			return
+		}
+		client_id = tok.User
+	} else {
+		client_id = generateRandom(8, runesDigital)
+	}
+
+	is_initiator, peers, messages, err := c.addClientToRoom(room_id, client_id, is_loopback)
 	if err != nil {
 		fmt.Printf("Error adding client to room: %v\n", err)
+		c.dash.onJoinResult(err.Error())
 		c.writeJoinResponse(w, err.Error(), make(map[string]interface{}), nil)
 		return
 	}
+	c.dash.onJoinResult(RESPONSE_SUCCESS)
 
 	fmt.Printf("User %s joined room %s\n", client_id, room_id)
-	params := c.getRoomParameters(room_id, client_id, is_initiator)
+	params := c.getRoomParameters(room_id, client_id, is_initiator, peers)
 	c.writeJoinResponse(w, "SUCCESS", params, messages)
 }
 
@@ -324,11 +472,18 @@ func (c *Collider) httpMessageHandler(w http.ResponseWriter, r *http.Request) {
 		c.writeMessageResponse(w, err.Error())
 	}
 	if !saved {
-		// Other client joined, forward to collider. Do this outside the lock.
-		// Note: this may fail in local dev server due to not having the right
-		// certificate file locally for SSL validation.
-		// Note: loopback scenario follows this code path.
-		c.sendMessageToCollider(w, room_id, client_id, message)
+		// Other client already joined: route to whichever collider holds
+		// its WebSocket, which with a clustered RoomStore may not be this
+		// process. Note: loopback scenario follows this code path.
+		fmt.Printf("Forwarding message to collider for room  %s client %s with message %s\n", room_id, client_id, message)
+		start := time.Now()
+		err := c.store.send(room_id, client_id, "", message)
+		c.dash.observeSendLatency(start)
+		if err != nil {
+			c.httpError("Failed to send the message: "+err.Error(), w)
+			return
+		}
+		c.writeMessageResponse(w, RESPONSE_SUCCESS)
 	} else {
 		c.writeMessageResponse(w, RESPONSE_SUCCESS)
 	}
@@ -406,12 +561,15 @@ func (c *Collider) httpHandler(w http.ResponseWriter, r *http.Request) {
 			c.httpError("Empty request body", w)
 			return
 		}
-		if err := c.roomTable.send(rid, cid, m); err != nil {
+		start := time.Now()
+		err = c.store.send(rid, cid, "", m)
+		c.dash.observeSendLatency(start)
+		if err != nil {
 			c.httpError("Failed to send the message: "+err.Error(), w)
 			return
 		}
 	case "DELETE":
-		c.roomTable.remove(rid, cid)
+		c.store.removeClient(rid, cid)
 	default:
 		return
 	}
@@ -419,38 +577,87 @@ func (c *Collider) httpHandler(w http.ResponseWriter, r *http.Request) {
 	io.WriteString(w, "OK\n")
 }
 
-// wsHandler is a WebSocket server that handles requests from the WebSocket client in the form of:
+// wsHandler is the read pump for a WebSocket connection; it handles
+// requests from the WebSocket client in the form of:
 // 1. { 'cmd': 'register', 'roomid': $ROOM, 'clientid': $CLIENT' },
 // which binds the WebSocket client to a client ID and room ID.
 // A client should send this message only once right after the connection is open.
+// Setting 'encoding': 'binary' additionally switches every later frame on
+// this connection to raw binary WebSocket frames relayed verbatim to the
+// rest of the room, instead of the JSON commands below. The server still
+// needs to keep the connection alive, so a zero-length binary frame is
+// reserved as a ping/pong: the server sends one periodically, and the
+// client must echo one back (any frame refreshes the read deadline, so
+// replying with a zero-length frame rather than real signaling data is
+// what keeps it from being relayed to the peer as if it were one).
 // or
 // 2. { 'cmd': 'send', 'msg': $MSG }, which sends the message to the other client of the room.
 // It should be sent to the server only after 'regiser' has been sent.
 // The message may be cached by the server if the other client has not joined.
 //
+// Once registered, a companion write pump goroutine writes every relayed
+// frame and ping to ws, draining the channel the RoomStore hands back from
+// register() so a slow peer can't stall the goroutine delivering to it.
+// Error replies from wsError are still written directly by this read loop.
+//
 // Unexpected messages will cause the WebSocket connection to be closed.
 func (c *Collider) wsHandler(ws *websocket.Conn) {
 	var rid, cid string
 
 	registered := false
+	binary := false
 
 	var msg wsClientMsg
 loop:
 	for {
-		err := ws.SetReadDeadline(time.Now().Add(time.Duration(wsReadTimeoutSec) * time.Second))
+		err := ws.SetReadDeadline(time.Now().Add(wsPongWait))
 		if err != nil {
 			c.wsError("ws.SetReadDeadline error: "+err.Error(), ws)
 			break
 		}
 
+		if registered && binary {
+			var raw []byte
+			if err = websocket.Message.Receive(ws, &raw); err != nil {
+				if registered && c.backend != nil {
+					if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+						c.backend.Notify(backendEventTimeout, rid, cid)
+					}
+				}
+				if err.Error() != "EOF" {
+					c.wsError("websocket.Message.Receive error: "+err.Error(), ws)
+				}
+				break
+			}
+			if len(raw) == 0 {
+				// Read deadline was already refreshed above; this is the
+				// binary equivalent of a "pong", not a message to relay.
+				continue
+			}
+			start := time.Now()
+			c.store.sendBinary(rid, cid, "", raw)
+			c.dash.observeSendLatency(start)
+			continue
+		}
+
 		err = websocket.JSON.Receive(ws, &msg)
 		if err != nil {
+			if registered && c.backend != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					c.backend.Notify(backendEventTimeout, rid, cid)
+				}
+			}
 			if err.Error() != "EOF" {
 				c.wsError("websocket.JSON.Receive error: "+err.Error(), ws)
 			}
 			break
 		}
 
+		if msg.Cmd == "pong" {
+			// Read deadline was already refreshed above; nothing else to do.
+			continue
+		}
+
 		log.Printf("WebSocket received %s from room %s client %s\n", msg.Cmd, msg.RoomID, msg.ClientID)
 
 		switch msg.Cmd {
@@ -463,14 +670,30 @@ loop:
 				c.wsError("Invalid register request: missing 'clientid' or 'roomid'", ws)
 				break loop
 			}
-			if err = c.roomTable.register(msg.RoomID, msg.ClientID, ws); err != nil {
+			if c.auth != nil {
+				tok, verr := c.auth.Verify(msg.Token)
+				if verr != nil {
+					c.wsError("Invalid register request: "+verr.Error(), ws)
+					break loop
+				}
+				if tok.Room != msg.RoomID || tok.User != msg.ClientID {
+					c.wsError("Invalid register request: token does not match roomid/clientid", ws)
+					break loop
+				}
+			}
+			out, err := c.store.register(msg.RoomID, msg.ClientID, ws)
+			if err != nil {
 				c.wsError(err.Error(), ws)
 				break loop
 			}
 			registered, rid, cid = true, msg.RoomID, msg.ClientID
+			binary = msg.Encoding == "binary"
 			c.dash.incrWs()
 
-			defer c.roomTable.deregister(rid, cid)
+			go writePump(ws, out, binary)
+
+			defer c.store.deregister(rid, cid)
+			defer c.dash.decrWs()
 			break
 		case "send":
 			if !registered {
@@ -481,7 +704,9 @@ loop:
 				c.wsError("Invalid send request: missing 'msg'", ws)
 				break loop
 			}
-			c.roomTable.send(rid, cid, msg.Msg)
+			start := time.Now()
+			c.store.send(rid, cid, msg.ToClientID, msg.Msg)
+			c.dash.observeSendLatency(start)
 			break
 		default:
 			c.wsError("Invalid message: unexpected 'cmd'", ws)
@@ -492,6 +717,52 @@ loop:
 	ws.Close()
 }
 
+// writePump writes the relayed frames and pings of a registered WebSocket
+// connection; only wsError's error replies bypass it. It drains out, applying a write deadline to each frame, and periodically
+// sends a ping so a peer that stops responding gets its read deadline
+// expire instead of the connection hanging open indefinitely. binary must
+// match the encoding negotiated at register time: a binary connection's
+// ping is a zero-length binary frame (see wsHandler), not the JSON "ping"
+// command a binary client has no code path to parse.
+// It returns once out is closed (on deregister) or a write fails.
+func writePump(ws *websocket.Conn, out <-chan wsFrame, binary bool) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case frame, ok := <-out:
+			if !ok {
+				return
+			}
+			if err := ws.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+			var err error
+			if frame.binary {
+				err = websocket.Message.Send(ws, frame.payload)
+			} else {
+				err = websocket.Message.Send(ws, string(frame.payload))
+			}
+			if err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := ws.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+			var err error
+			if binary {
+				err = websocket.Message.Send(ws, []byte{})
+			} else {
+				err = websocket.JSON.Send(ws, wsClientMsg{Cmd: "ping"})
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
 func (c *Collider) httpError(msg string, w http.ResponseWriter) {
 	err := errors.New(msg)
 	http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -503,3 +774,31 @@ func (c *Collider) wsError(msg string, ws *websocket.Conn) {
 	sendServerErr(ws, msg)
 	c.dash.onWsErr(err)
 }
+
+// handshakeCountingListener performs the TLS handshake eagerly in Accept
+// so a failed handshake (bad cert, unsupported cipher, etc.) is counted
+// on the dashboard instead of surfacing only as a dropped connection deep
+// inside net/http.
+type handshakeCountingListener struct {
+	net.Listener
+	dash *dashboard
+}
+
+func (l *handshakeCountingListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		tlsConn, ok := conn.(*tls.Conn)
+		if !ok {
+			return conn, nil
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			l.dash.onTlsHandshakeFailure()
+			conn.Close()
+			continue
+		}
+		return tlsConn, nil
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// dashboard accumulates the counters surfaced by the /status endpoint and,
+// in parallel, a set of Prometheus collectors for pull-based scraping via
+// /metrics. /status stays a cheap JSON snapshot for humans and scripts;
+// /metrics is what production operators should actually alert on.
+type dashboard struct {
+	mu          sync.Mutex
+	wsCount     int
+	httpErrs    int
+	wsErrs      int
+	lastHttpErr string
+	lastWsErr   string
+
+	registry *prometheus.Registry
+
+	joinResults          *prometheus.CounterVec
+	activeWs             prometheus.Gauge
+	activeRooms          prometheus.Gauge
+	queueDepth           prometheus.Histogram
+	sendLatency          prometheus.Histogram
+	tlsHandshakeFailures prometheus.Counter
+}
+
+func newDashboard() *dashboard {
+	d := &dashboard{
+		registry: prometheus.NewRegistry(),
+		joinResults: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "collider_join_results_total",
+			Help: "Count of room join attempts by result code (SUCCESS, ROOM_FULL, DUPLICATE_CLIENT, UNKNOWN_ROOM, INVALID_REQUEST, ...).",
+		}, []string{"result"}),
+		activeWs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "collider_active_ws_connections",
+			Help: "Number of currently registered WebSocket connections.",
+		}),
+		activeRooms: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "collider_active_rooms",
+			Help: "Number of rooms currently occupied by at least one client.",
+		}),
+		queueDepth: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "collider_queued_message_depth",
+			Help:    "Number of offer/candidate messages queued for a client awaiting its peer.",
+			Buckets: prometheus.LinearBuckets(0, 2, 10),
+		}),
+		sendLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "collider_message_forward_latency_seconds",
+			Help:    "Time to forward a signaling message to its destination(s).",
+			Buckets: prometheus.DefBuckets,
+		}),
+		tlsHandshakeFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "collider_tls_handshake_failures_total",
+			Help: "Count of TLS handshakes that failed before an HTTP/WebSocket request could be served.",
+		}),
+	}
+	d.registry.MustRegister(d.joinResults, d.activeWs, d.activeRooms, d.queueDepth, d.sendLatency, d.tlsHandshakeFailures)
+	return d
+}
+
+// Handler serves the Prometheus exposition format for /metrics.
+func (d *dashboard) Handler() http.Handler {
+	return promhttp.HandlerFor(d.registry, promhttp.HandlerOpts{})
+}
+
+func (d *dashboard) incrWs() {
+	d.mu.Lock()
+	d.wsCount++
+	d.mu.Unlock()
+	d.activeWs.Inc()
+}
+
+func (d *dashboard) decrWs() {
+	d.activeWs.Dec()
+}
+
+func (d *dashboard) onHttpErr(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.httpErrs++
+	d.lastHttpErr = err.Error()
+}
+
+func (d *dashboard) onWsErr(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.wsErrs++
+	d.lastWsErr = err.Error()
+}
+
+func (d *dashboard) onJoinResult(result string) {
+	d.joinResults.WithLabelValues(result).Inc()
+}
+
+func (d *dashboard) onRoomCreated() {
+	d.activeRooms.Inc()
+}
+
+func (d *dashboard) onRoomClosed() {
+	d.activeRooms.Dec()
+}
+
+func (d *dashboard) observeQueueDepth(depth int) {
+	d.queueDepth.Observe(float64(depth))
+}
+
+func (d *dashboard) observeSendLatency(start time.Time) {
+	d.sendLatency.Observe(time.Since(start).Seconds())
+}
+
+func (d *dashboard) onTlsHandshakeFailure() {
+	d.tlsHandshakeFailures.Inc()
+}
+
+// report is the JSON payload served by the /status endpoint.
+type report struct {
+	WsCount     int    `json:"ws_count"`
+	HttpErrs    int    `json:"http_errs"`
+	WsErrs      int    `json:"ws_errs"`
+	LastHttpErr string `json:"last_http_err,omitempty"`
+	LastWsErr   string `json:"last_ws_err,omitempty"`
+}
+
+func (d *dashboard) getReport() report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return report{
+		WsCount:     d.wsCount,
+		HttpErrs:    d.httpErrs,
+		WsErrs:      d.wsErrs,
+		LastHttpErr: d.lastHttpErr,
+		LastWsErr:   d.lastWsErr,
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"ngrtc/collider/proto"
+
+	"google.golang.org/grpc"
+)
+
+// GRPCServer exposes the same join/send/leave signaling operations as the
+// JSON WebSocket frontend, over gRPC, for non-browser clients (mobile
+// SDKs, server-to-server bridges) that want a typed binary transport
+// instead of JSON-over-WebSocket. It reuses Collider's room bookkeeping
+// (addClientToRoom/removeClientFromRoom/saveMessageFromClient) so both
+// frontends see the same rooms.
+type GRPCServer struct {
+	proto.UnimplementedColliderServer
+
+	c *Collider
+
+	mu      sync.Mutex
+	streams map[string]map[string]chan *proto.RoomEvent // room_id -> client_id -> outbound events
+}
+
+func newGRPCServer(c *Collider) *GRPCServer {
+	return &GRPCServer{
+		c:       c,
+		streams: make(map[string]map[string]chan *proto.RoomEvent),
+	}
+}
+
+func (g *GRPCServer) Join(ctx context.Context, req *proto.JoinRequest) (*proto.JoinResponse, error) {
+	room_id := req.GetRoomId()
+	client_id, err := g.authenticate(room_id, req.GetToken())
+	if err != nil {
+		return &proto.JoinResponse{Result: RESPONSE_INVALID_REQUEST}, nil
+	}
+
+	is_initiator, peers, messages, err := g.c.addClientToRoom(room_id, client_id, req.GetIsLoopback())
+	if err != nil {
+		return &proto.JoinResponse{Result: err.Error()}, nil
+	}
+	g.notifyRoom(room_id, client_id, "", proto.RoomEvent_PEER_JOINED, "")
+	return &proto.JoinResponse{
+		Result:      RESPONSE_SUCCESS,
+		ClientId:    client_id,
+		IsInitiator: is_initiator,
+		Peers:       peers,
+		Messages:    messages,
+	}, nil
+}
+
+// authenticate mirrors the HTTP/WS join path: when g.c.auth is configured,
+// token must verify and be minted for room_id, and the client ID is
+// whatever the token names rather than caller-chosen; with no AuthManager
+// configured it falls back to a random client ID, same as an unauthenticated
+// HTTP join.
+func (g *GRPCServer) authenticate(room_id, token string) (client_id string, err error) {
+	if g.c.auth == nil {
+		return generateRandom(8, runesDigital), nil
+	}
+	tok, err := g.c.auth.Verify(token)
+	if err != nil {
+		return "", err
+	}
+	if tok.Room != room_id {
+		return "", fmt.Errorf("token is for room %s, not %s", tok.Room, room_id)
+	}
+	return tok.User, nil
+}
+
+func (g *GRPCServer) Leave(ctx context.Context, req *proto.LeaveRequest) (*proto.LeaveResponse, error) {
+	if err := g.c.removeClientFromRoom(req.GetRoomId(), req.GetClientId()); err != nil {
+		return &proto.LeaveResponse{Result: err.Error()}, nil
+	}
+	g.notifyRoom(req.GetRoomId(), req.GetClientId(), "", proto.RoomEvent_PEER_LEFT, "")
+	g.closeStream(req.GetRoomId(), req.GetClientId())
+	return &proto.LeaveResponse{Result: RESPONSE_SUCCESS}, nil
+}
+
+func (g *GRPCServer) Send(ctx context.Context, req *proto.SendRequest) (*proto.SendResponse, error) {
+	saved, err := g.c.saveMessageFromClient(req.GetRoomId(), req.GetClientId(), req.GetMessage())
+	if err != nil {
+		return &proto.SendResponse{Result: err.Error()}, nil
+	}
+	if !saved {
+		delivered := g.notifyRoom(req.GetRoomId(), req.GetClientId(), req.GetToClientId(), proto.RoomEvent_MESSAGE, req.GetMessage())
+		// store.send only reaches peers registered with the RoomStore (i.e.
+		// over /ws); a peer that joined over gRPC has no such registration,
+		// so store.send reports RESPONSE_UNKNOWN_CLIENT for it even though
+		// notifyRoom just delivered the message over its stream above.
+		if err := g.c.store.send(req.GetRoomId(), req.GetClientId(), req.GetToClientId(), req.GetMessage()); err != nil && !delivered {
+			return &proto.SendResponse{Result: err.Error()}, nil
+		}
+	}
+	return &proto.SendResponse{Result: RESPONSE_SUCCESS}, nil
+}
+
+// Register implements the bidirectional stream: the first RegisterRequest
+// binds the stream to a room/client so RoomEvents can be pushed back, and
+// any subsequent request on the stream carries a signaling payload, same
+// as a WebSocket "send" command.
+func (g *GRPCServer) Register(stream proto.Collider_RegisterServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	room_id, client_id := req.GetRoomId(), req.GetClientId()
+	if room_id == "" || client_id == "" {
+		return fmt.Errorf("Register: missing room_id or client_id")
+	}
+	if g.c.auth != nil {
+		tok, err := g.c.auth.Verify(req.GetToken())
+		if err != nil {
+			return fmt.Errorf("Register: %w", err)
+		}
+		if tok.Room != room_id || tok.User != client_id {
+			return fmt.Errorf("Register: token does not match room_id/client_id")
+		}
+	}
+
+	events := g.openStream(room_id, client_id)
+	defer g.closeStream(room_id, client_id)
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if req.GetMessage() != "" {
+				if _, err := g.Send(stream.Context(), &proto.SendRequest{
+					RoomId:   room_id,
+					ClientId: client_id,
+					Message:  req.GetMessage(),
+				}); err != nil {
+					errCh <- err
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case ev := <-events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		case err := <-errCh:
+			return err
+		}
+	}
+}
+
+func (g *GRPCServer) openStream(room_id, client_id string) chan *proto.RoomEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	room, ok := g.streams[room_id]
+	if !ok {
+		room = make(map[string]chan *proto.RoomEvent)
+		g.streams[room_id] = room
+	}
+	ch := make(chan *proto.RoomEvent, 16)
+	room[client_id] = ch
+	return ch
+}
+
+func (g *GRPCServer) closeStream(room_id, client_id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	room, ok := g.streams[room_id]
+	if !ok {
+		return
+	}
+	delete(room, client_id)
+	if len(room) == 0 {
+		delete(g.streams, room_id)
+	}
+}
+
+// notifyRoom pushes an event to the gRPC stream registered for
+// to_client_id, or to every stream in room_id other than from_client_id
+// when to_client_id is empty. It reports whether at least one stream
+// actually received the event, so Send can tell whether a RoomStore
+// delivery failure alongside it still means nobody got the message.
+func (g *GRPCServer) notifyRoom(room_id, from_client_id, to_client_id string, kind proto.RoomEvent_Kind, msg string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delivered := false
+	for client_id, ch := range g.streams[room_id] {
+		if to_client_id != "" {
+			if client_id != to_client_id {
+				continue
+			}
+		} else if client_id == from_client_id {
+			continue
+		}
+		select {
+		case ch <- &proto.RoomEvent{Kind: kind, PeerId: from_client_id, Message: msg}:
+			delivered = true
+		default:
+			fmt.Printf("notifyRoom: dropping event for slow client %s in room %s\n", client_id, room_id)
+		}
+	}
+	return delivered
+}
+
+// listenAndServeGRPC starts the gRPC signaling frontend on grpcPort,
+// blocking until it errors. Collider.Run launches it in its own goroutine
+// alongside the HTTP/WebSocket listener so both frontends share one
+// Collider and its RoomStore.
+//
+// The server is forced onto proto.Codec() (see proto/codec.go) because the
+// types in the proto package carry no protoc-generated ProtoReflect
+// descriptors in this checked-in copy, so they can't satisfy grpc's default
+// "proto" codec.
+//
+// This does not also serve gRPC-Web: doing so needs a wrapper such as
+// github.com/improbable-eng/grpc-web fronting the same grpc.Server, which
+// isn't wired up here. Browser clients should keep using /ws.
+func (c *Collider) listenAndServeGRPC(grpcPort int) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
+	if err != nil {
+		return fmt.Errorf("listenAndServeGRPC: listen: %w", err)
+	}
+	s := grpc.NewServer(grpc.ForceServerCodec(proto.Codec()))
+	proto.RegisterColliderServer(s, newGRPCServer(c))
+	return s.Serve(lis)
+}
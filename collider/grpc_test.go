@@ -0,0 +1,189 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"ngrtc/collider/proto"
+
+	"google.golang.org/grpc"
+)
+
+func TestGRPCServerJoinReportsInitiatorAndPeers(t *testing.T) {
+	g := newGRPCServer(NewCollider(""))
+
+	first, err := g.Join(context.Background(), &proto.JoinRequest{RoomId: "room1"})
+	if err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+	if first.GetResult() != RESPONSE_SUCCESS || !first.GetIsInitiator() {
+		t.Fatalf("first Join = %+v, want success+initiator", first)
+	}
+
+	second, err := g.Join(context.Background(), &proto.JoinRequest{RoomId: "room1"})
+	if err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+	if second.GetResult() != RESPONSE_SUCCESS || second.GetIsInitiator() {
+		t.Fatalf("second Join = %+v, want success+non-initiator", second)
+	}
+	if len(second.GetPeers()) != 1 || second.GetPeers()[0] != first.GetClientId() {
+		t.Fatalf("second Join peers = %v, want [%s]", second.GetPeers(), first.GetClientId())
+	}
+}
+
+func TestGRPCServerSendBetweenTwoGRPCClientsDeliversOverTheStream(t *testing.T) {
+	g := newGRPCServer(NewCollider(""))
+
+	alice, err := g.Join(context.Background(), &proto.JoinRequest{RoomId: "room1"})
+	if err != nil {
+		t.Fatalf("Join alice: %v", err)
+	}
+	bob, err := g.Join(context.Background(), &proto.JoinRequest{RoomId: "room1"})
+	if err != nil {
+		t.Fatalf("Join bob: %v", err)
+	}
+
+	events := g.openStream("room1", bob.GetClientId())
+	defer g.closeStream("room1", bob.GetClientId())
+
+	resp, err := g.Send(context.Background(), &proto.SendRequest{
+		RoomId:     "room1",
+		ClientId:   alice.GetClientId(),
+		ToClientId: bob.GetClientId(),
+		Message:    "offer",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	// Both peers joined over gRPC, so the RoomStore has never heard of
+	// either of them; Send must not surface RoomStore's UNKNOWN_CLIENT once
+	// notifyRoom has actually delivered the message over bob's stream.
+	if resp.GetResult() != RESPONSE_SUCCESS {
+		t.Fatalf("Send result = %q, want %q", resp.GetResult(), RESPONSE_SUCCESS)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.GetMessage() != "offer" {
+			t.Fatalf("delivered message = %q, want %q", ev.GetMessage(), "offer")
+		}
+	default:
+		t.Fatal("bob's stream did not receive the message")
+	}
+}
+
+func TestGRPCServerLeaveNotifiesRemainingPeer(t *testing.T) {
+	g := newGRPCServer(NewCollider(""))
+
+	alice, err := g.Join(context.Background(), &proto.JoinRequest{RoomId: "room1"})
+	if err != nil {
+		t.Fatalf("Join alice: %v", err)
+	}
+	bob, err := g.Join(context.Background(), &proto.JoinRequest{RoomId: "room1"})
+	if err != nil {
+		t.Fatalf("Join bob: %v", err)
+	}
+
+	events := g.openStream("room1", bob.GetClientId())
+	defer g.closeStream("room1", bob.GetClientId())
+
+	resp, err := g.Leave(context.Background(), &proto.LeaveRequest{RoomId: "room1", ClientId: alice.GetClientId()})
+	if err != nil {
+		t.Fatalf("Leave: %v", err)
+	}
+	if resp.GetResult() != RESPONSE_SUCCESS {
+		t.Fatalf("Leave result = %q, want %q", resp.GetResult(), RESPONSE_SUCCESS)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.GetKind() != proto.RoomEvent_PEER_LEFT {
+			t.Fatalf("event kind = %v, want PEER_LEFT", ev.GetKind())
+		}
+	default:
+		t.Fatal("bob's stream did not receive the PEER_LEFT event")
+	}
+}
+
+func TestGRPCServerRegisterRelaysSendToRoomPeer(t *testing.T) {
+	g := newGRPCServer(NewCollider(""))
+
+	alice, err := g.Join(context.Background(), &proto.JoinRequest{RoomId: "room1"})
+	if err != nil {
+		t.Fatalf("Join alice: %v", err)
+	}
+	bob, err := g.Join(context.Background(), &proto.JoinRequest{RoomId: "room1"})
+	if err != nil {
+		t.Fatalf("Join bob: %v", err)
+	}
+
+	bobEvents := g.openStream("room1", bob.GetClientId())
+	defer g.closeStream("room1", bob.GetClientId())
+
+	stream := newFakeRegisterStream()
+	// Queued up front: Register's own goroutine only starts Recv-ing the
+	// second request once the first has bound the stream and opened alice's
+	// event channel, so there's no race reading these out of order.
+	stream.recvCh <- &proto.RegisterRequest{RoomId: "room1", ClientId: alice.GetClientId()}
+	stream.recvCh <- &proto.RegisterRequest{RoomId: "room1", ClientId: alice.GetClientId(), Message: "offer"}
+
+	registerDone := make(chan error, 1)
+	go func() { registerDone <- g.Register(stream) }()
+
+	select {
+	case ev := <-bobEvents:
+		if ev.GetMessage() != "offer" {
+			t.Fatalf("delivered message = %q, want %q", ev.GetMessage(), "offer")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("bob did not receive alice's message relayed through Register")
+	}
+
+	close(stream.recvCh)
+	select {
+	case err := <-registerDone:
+		if err != io.EOF {
+			t.Fatalf("Register returned %v, want io.EOF once its stream closed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Register did not return after its stream closed")
+	}
+}
+
+// fakeRegisterStream is a minimal stand-in for proto.Collider_RegisterServer,
+// just enough to drive GRPCServer.Register without a real gRPC connection.
+type fakeRegisterStream struct {
+	grpc.ServerStream
+	recvCh chan *proto.RegisterRequest
+	sendCh chan *proto.RoomEvent
+}
+
+func newFakeRegisterStream() *fakeRegisterStream {
+	return &fakeRegisterStream{
+		recvCh: make(chan *proto.RegisterRequest, 16),
+		sendCh: make(chan *proto.RoomEvent, 16),
+	}
+}
+
+func (s *fakeRegisterStream) Context() context.Context { return context.Background() }
+
+func (s *fakeRegisterStream) Send(ev *proto.RoomEvent) error {
+	s.sendCh <- ev
+	return nil
+}
+
+func (s *fakeRegisterStream) Recv() (*proto.RegisterRequest, error) {
+	req, ok := <-s.recvCh
+	if !ok {
+		return nil, io.EOF
+	}
+	return req, nil
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import "golang.org/x/net/websocket"
+
+// wsClientMsg is the JSON envelope sent by a WebSocket client. ToClientID
+// targets a single room peer for a "send" command; when it's omitted the
+// message is broadcast to every other occupant of the room. Encoding may
+// be set to "binary" on a "register" command so the client can instead
+// exchange raw binary WebSocket frames (e.g. pre-serialized protobuf)
+// after registering, without a JSON wrapper.
+type wsClientMsg struct {
+	Cmd        string `json:"cmd"`
+	RoomID     string `json:"roomid"`
+	ClientID   string `json:"clientid"`
+	ToClientID string `json:"to_client_id,omitempty"`
+	Msg        string `json:"msg"`
+	Token      string `json:"token,omitempty"`
+	Encoding   string `json:"encoding,omitempty"`
+}
+
+// wsServerMsg is the JSON envelope collider sends back down a WebSocket
+// connection, used for out-of-band errors that aren't a Response.
+type wsServerMsg struct {
+	Error string `json:"error"`
+}
+
+func sendServerErr(ws *websocket.Conn, msg string) {
+	websocket.JSON.Send(ws, wsServerMsg{Error: msg})
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package proto
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// codec marshals this package's request/response types to real protobuf
+// wire bytes, using the field numbers already recorded in each struct's
+// `protobuf` tag (the same tags protoc-gen-go would emit from
+// collider.proto). It's driven by reflection instead of generated
+// Marshal/Unmarshal methods because the types in collider.pb.go have no
+// ProtoReflect descriptors (see the comment at the bottom of that file),
+// so they can't go through the real protobuf runtime or satisfy grpc's
+// default "proto" codec. It only handles the string/bool/enum/repeated-string
+// field shapes collider.pb.go actually uses -- no nested messages, maps, or
+// numeric types beyond the int32-backed enum, because nothing here needs
+// them.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return marshalMessage(reflect.ValueOf(v).Elem())
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return unmarshalMessage(data, reflect.ValueOf(v).Elem())
+}
+
+func (codec) Name() string { return "collider-pb" }
+
+// Codec returns the wire codec used to transport this package's messages.
+// Wire it into the server with grpc.ForceServerCodec(proto.Codec()) and
+// into a client with grpc.WithDefaultCallOptions(grpc.ForceCodec(proto.Codec())).
+func Codec() interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	Name() string
+} {
+	return codec{}
+}
+
+// protoField is one struct field's protobuf field number, as parsed out of
+// its `protobuf:"<wiretype>,<number>,..."` tag.
+type protoField struct {
+	index  int
+	number protowire.Number
+}
+
+func protoFields(t reflect.Type) []protoField {
+	fields := make([]protoField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("protobuf")
+		if tag == "" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts) < 2 {
+			continue
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		fields = append(fields, protoField{index: i, number: protowire.Number(n)})
+	}
+	return fields
+}
+
+func marshalMessage(sv reflect.Value) ([]byte, error) {
+	var out []byte
+	for _, f := range protoFields(sv.Type()) {
+		fv := sv.Field(f.index)
+		switch fv.Kind() {
+		case reflect.String:
+			if fv.Len() == 0 {
+				continue
+			}
+			out = protowire.AppendTag(out, f.number, protowire.BytesType)
+			out = protowire.AppendString(out, fv.String())
+		case reflect.Bool:
+			if !fv.Bool() {
+				continue
+			}
+			out = protowire.AppendTag(out, f.number, protowire.VarintType)
+			out = protowire.AppendVarint(out, protowire.EncodeBool(true))
+		case reflect.Int32:
+			if fv.Int() == 0 {
+				continue
+			}
+			out = protowire.AppendTag(out, f.number, protowire.VarintType)
+			out = protowire.AppendVarint(out, uint64(fv.Int()))
+		case reflect.Slice:
+			if fv.Type().Elem().Kind() != reflect.String {
+				return nil, fmt.Errorf("proto: codec: field %d: unsupported slice element %s", f.number, fv.Type().Elem())
+			}
+			for i := 0; i < fv.Len(); i++ {
+				out = protowire.AppendTag(out, f.number, protowire.BytesType)
+				out = protowire.AppendString(out, fv.Index(i).String())
+			}
+		default:
+			return nil, fmt.Errorf("proto: codec: field %d: unsupported kind %s", f.number, fv.Kind())
+		}
+	}
+	return out, nil
+}
+
+func unmarshalMessage(data []byte, sv reflect.Value) error {
+	indexByNumber := make(map[protowire.Number]int)
+	for _, f := range protoFields(sv.Type()) {
+		indexByNumber[f.number] = f.index
+	}
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		index, known := indexByNumber[num]
+		if !known {
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		fv := sv.Field(index)
+		switch fv.Kind() {
+		case reflect.String:
+			s, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fv.SetString(s)
+			data = data[n:]
+		case reflect.Bool:
+			val, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fv.SetBool(protowire.DecodeBool(val))
+			data = data[n:]
+		case reflect.Int32:
+			val, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fv.SetInt(int64(val))
+			data = data[n:]
+		case reflect.Slice:
+			s, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fv.Set(reflect.Append(fv, reflect.ValueOf(s)))
+			data = data[n:]
+		default:
+			return fmt.Errorf("proto: codec: field %d: unsupported kind %s", num, fv.Kind())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package proto
+
+import "testing"
+
+func TestCodecRoundTripJoinResponse(t *testing.T) {
+	want := &JoinResponse{
+		Result:      "SUCCESS",
+		ClientId:    "alice",
+		IsInitiator: true,
+		Peers:       []string{"bob", "carol"},
+		Messages:    []string{"offer", "candidate"},
+	}
+	data, err := Codec().Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got := &JoinResponse{}
+	if err := Codec().Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.Result != want.Result || got.ClientId != want.ClientId || got.IsInitiator != want.IsInitiator {
+		t.Fatalf("Unmarshal returned %+v, want %+v", got, want)
+	}
+	if len(got.Peers) != len(want.Peers) || len(got.Messages) != len(want.Messages) {
+		t.Fatalf("Unmarshal returned %+v, want %+v", got, want)
+	}
+	for i := range want.Peers {
+		if got.Peers[i] != want.Peers[i] {
+			t.Fatalf("Peers[%d] = %q, want %q", i, got.Peers[i], want.Peers[i])
+		}
+	}
+}
+
+func TestCodecRoundTripRoomEventEnum(t *testing.T) {
+	want := &RoomEvent{Kind: RoomEvent_PEER_LEFT, PeerId: "bob", Message: "bye"}
+	data, err := Codec().Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got := &RoomEvent{}
+	if err := Codec().Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if *got != *want {
+		t.Fatalf("Unmarshal returned %+v, want %+v", got, want)
+	}
+}
+
+// Zero-valued scalar fields are omitted from the wire, same as proto3's own
+// default-value-means-absent rule, so a zero-valued message round-trips to
+// an empty encoding rather than a tag and an empty value per field.
+func TestCodecOmitsZeroValues(t *testing.T) {
+	data, err := Codec().Marshal(&SendRequest{})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if len(data) != 0 {
+		t.Fatalf("Marshal of a zero-valued message returned %d bytes, want 0", len(data))
+	}
+}
+
+func TestCodecUnmarshalSkipsUnknownFields(t *testing.T) {
+	// A JoinResponse and a LeaveResponse share field 1 (string "result"), so
+	// encoding one and decoding it as the other should work. JoinResponse's
+	// client_id (field 2) is a field the destination type doesn't declare;
+	// confirm it's skipped rather than rejected.
+	data, err := Codec().Marshal(&JoinResponse{Result: "SUCCESS", ClientId: "alice"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got := &LeaveResponse{}
+	if err := Codec().Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.Result != "SUCCESS" {
+		t.Fatalf("Result = %q, want %q", got.Result, "SUCCESS")
+	}
+}
@@ -0,0 +1,216 @@
+// Hand-written to match the shape protoc-gen-go-grpc would produce from
+// collider.proto, NOT real protoc output: there is no protoc/protoc-gen-go-grpc
+// in this build environment. Running protoc over collider.proto will not
+// regenerate or touch this file; see collider.pb.go and proto/codec.go.
+
+package proto
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// ColliderClient is the client API for Collider service.
+type ColliderClient interface {
+	Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinResponse, error)
+	Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveResponse, error)
+	Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendResponse, error)
+	Register(ctx context.Context, opts ...grpc.CallOption) (Collider_RegisterClient, error)
+}
+
+type colliderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewColliderClient returns a ColliderClient backed by cc. Callers must
+// dial cc with grpc.WithDefaultCallOptions(grpc.ForceCodec(proto.Codec()))
+// so requests/responses are marshaled in the format this package's
+// hand-written types support; see codec.go.
+func NewColliderClient(cc grpc.ClientConnInterface) ColliderClient {
+	return &colliderClient{cc}
+}
+
+func (c *colliderClient) Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinResponse, error) {
+	out := new(JoinResponse)
+	if err := c.cc.Invoke(ctx, "/collider.proto.Collider/Join", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *colliderClient) Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveResponse, error) {
+	out := new(LeaveResponse)
+	if err := c.cc.Invoke(ctx, "/collider.proto.Collider/Leave", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *colliderClient) Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendResponse, error) {
+	out := new(SendResponse)
+	if err := c.cc.Invoke(ctx, "/collider.proto.Collider/Send", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *colliderClient) Register(ctx context.Context, opts ...grpc.CallOption) (Collider_RegisterClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Collider_ServiceDesc.Streams[0], "/collider.proto.Collider/Register", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &colliderRegisterClient{stream}, nil
+}
+
+// Collider_RegisterClient is the bidirectional stream used by Register.
+type Collider_RegisterClient interface {
+	Send(*RegisterRequest) error
+	Recv() (*RoomEvent, error)
+	grpc.ClientStream
+}
+
+type colliderRegisterClient struct {
+	grpc.ClientStream
+}
+
+func (x *colliderRegisterClient) Send(m *RegisterRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *colliderRegisterClient) Recv() (*RoomEvent, error) {
+	m := new(RoomEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ColliderServer is the server API for Collider service. Implementations
+// must embed UnimplementedColliderServer for forward compatibility.
+type ColliderServer interface {
+	Join(context.Context, *JoinRequest) (*JoinResponse, error)
+	Leave(context.Context, *LeaveRequest) (*LeaveResponse, error)
+	Send(context.Context, *SendRequest) (*SendResponse, error)
+	Register(Collider_RegisterServer) error
+}
+
+// Collider_RegisterServer is the bidirectional stream used by Register.
+type Collider_RegisterServer interface {
+	Send(*RoomEvent) error
+	Recv() (*RegisterRequest, error)
+	grpc.ServerStream
+}
+
+type colliderRegisterServer struct {
+	grpc.ServerStream
+}
+
+func (x *colliderRegisterServer) Send(m *RoomEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *colliderRegisterServer) Recv() (*RegisterRequest, error) {
+	m := new(RegisterRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UnimplementedColliderServer must be embedded by ColliderServer
+// implementations that don't implement every method, matching the
+// forward-compatibility convention generated gRPC code relies on.
+type UnimplementedColliderServer struct{}
+
+func (UnimplementedColliderServer) Join(context.Context, *JoinRequest) (*JoinResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Join not implemented")
+}
+
+func (UnimplementedColliderServer) Leave(context.Context, *LeaveRequest) (*LeaveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leave not implemented")
+}
+
+func (UnimplementedColliderServer) Send(context.Context, *SendRequest) (*SendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Send not implemented")
+}
+
+func (UnimplementedColliderServer) Register(Collider_RegisterServer) error {
+	return status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+
+// RegisterColliderServer registers srv's implementation with s.
+func RegisterColliderServer(s grpc.ServiceRegistrar, srv ColliderServer) {
+	s.RegisterService(&Collider_ServiceDesc, srv)
+}
+
+func _Collider_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ColliderServer).Join(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/collider.proto.Collider/Join"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ColliderServer).Join(ctx, req.(*JoinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Collider_Leave_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ColliderServer).Leave(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/collider.proto.Collider/Leave"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ColliderServer).Leave(ctx, req.(*LeaveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Collider_Send_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ColliderServer).Send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/collider.proto.Collider/Send"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ColliderServer).Send(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Collider_Register_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ColliderServer).Register(&colliderRegisterServer{stream})
+}
+
+// Collider_ServiceDesc is the grpc.ServiceDesc for the Collider service.
+var Collider_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "collider.proto.Collider",
+	HandlerType: (*ColliderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Join", Handler: _Collider_Join_Handler},
+		{MethodName: "Leave", Handler: _Collider_Leave_Handler},
+		{MethodName: "Send", Handler: _Collider_Send_Handler},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Register",
+			Handler:       _Collider_Register_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "collider.proto",
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// writeChanDepth bounds how many live frames can be queued for a
+// connection's write pump before send() starts dropping them rather than
+// blocking the caller, which may be running on another client's own read
+// loop.
+const writeChanDepth = 32
+
+// wsFrame is a single frame handed off to a connection's write pump.
+// binary selects a WebSocket binary frame instead of the usual text frame,
+// so a peer that negotiated binary encoding can relay pre-serialized
+// protobuf payloads without a JSON wrapper.
+type wsFrame struct {
+	binary  bool
+	payload []byte
+}
+
+// client represents a single participant occupying a room. It tracks the
+// participant's role in the call and any offer/candidate messages it sent
+// that are queued while its peer has not yet joined or reconnected.
+type client struct {
+	id           string
+	is_initiator bool
+	msgs         []string
+	ws           *websocket.Conn
+	// out is drained by this connection's write pump and is non-nil only
+	// while the client is registered; send() pushes onto it instead of
+	// writing to ws directly so a slow consumer can't stall the sender.
+	out chan wsFrame
+}
+
+func (cl *client) enqueue(msg string) {
+	cl.msgs = append(cl.msgs, msg)
+}
+
+// room holds the clients currently occupying a single room_id.
+type room struct {
+	id      string
+	clients map[string]*client
+}
+
+func newRoom(id string) *room {
+	return &room{id: id, clients: make(map[string]*client)}
+}
+
+// client returns the existing client for client_id, creating it if this is
+// its first time joining the room.
+func (r *room) client(client_id string) *client {
+	cl, ok := r.clients[client_id]
+	if !ok {
+		cl = &client{id: client_id}
+		r.clients[client_id] = cl
+	}
+	return cl
+}
+
+// other_client returns an occupant other than client_id; in a room of more
+// than two participants, which one it returns is not defined.
+func (r *room) other_client(client_id string) (*client, error) {
+	for id, cl := range r.clients {
+		if id != client_id {
+			return cl, nil
+		}
+	}
+	return nil, errors.New(RESPONSE_UNKNOWN_CLIENT)
+}
+
+func (r *room) remove(client_id string) {
+	delete(r.clients, client_id)
+}
+
+// roomTable is the in-memory RoomStore implementation used when collider
+// runs as a single process. It keeps every room, client and queued message
+// in local maps, which is why today two peers of a call must land on the
+// same collider instance.
+type roomTable struct {
+	mu         sync.Mutex
+	rooms      map[string]*room
+	roomSrvUrl string
+	timeout    time.Duration
+}
+
+func newRoomTable(timeout time.Duration, roomSrvUrl string) *roomTable {
+	return &roomTable{
+		rooms:      make(map[string]*room),
+		roomSrvUrl: roomSrvUrl,
+		timeout:    timeout,
+	}
+}
+
+func (rt *roomTable) room(room_id string) *room {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.roomLocked(room_id)
+}
+
+// roomLocked looks up or creates a room. Callers must hold rt.mu.
+func (rt *roomTable) roomLocked(room_id string) *room {
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		r = newRoom(room_id)
+		rt.rooms[room_id] = r
+	}
+	return r
+}
+
+func (rt *roomTable) roomExists(room_id string) bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	_, ok := rt.rooms[room_id]
+	return ok
+}
+
+func (rt *roomTable) clientExists(room_id, client_id string) bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		return false
+	}
+	_, ok = r.clients[client_id]
+	return ok
+}
+
+func (rt *roomTable) occupancy(room_id string) int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return len(rt.roomLocked(room_id).clients)
+}
+
+// addClient enrolls client_id in room_id, returning the ids of its peers
+// that were already present so the caller can decide initiator/messages
+// and report the room's occupants back to the joining client. The first
+// client to ever occupy a room is its initiator; every later joiner is
+// recorded as a non-initiator participant in the mesh.
+func (rt *roomTable) addClient(room_id, client_id string) (peers []string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r := rt.roomLocked(room_id)
+	for id := range r.clients {
+		peers = append(peers, id)
+	}
+	cl := r.client(client_id)
+	cl.is_initiator = len(peers) == 0
+	return
+}
+
+func (rt *roomTable) removeClient(room_id, client_id string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		return
+	}
+	wasInitiator := false
+	if cl, ok := r.clients[client_id]; ok {
+		wasInitiator = cl.is_initiator
+	}
+	r.remove(client_id)
+	if len(r.clients) == 0 {
+		delete(rt.rooms, room_id)
+		return
+	}
+	if wasInitiator {
+		// Promote an arbitrary survivor so the room always has exactly
+		// one initiator, matching the role bookkeeping clients expect.
+		for _, cl := range r.clients {
+			cl.is_initiator = true
+			break
+		}
+	}
+}
+
+// queueDepth reports how many messages are currently queued for
+// client_id, for reporting via the dashboard.
+func (rt *roomTable) queueDepth(room_id, client_id string) int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		return 0
+	}
+	cl, ok := r.clients[client_id]
+	if !ok {
+		return 0
+	}
+	return len(cl.msgs)
+}
+
+func (rt *roomTable) drainMessages(room_id, client_id string) []string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		return nil
+	}
+	cl, ok := r.clients[client_id]
+	if !ok {
+		return nil
+	}
+	msgs := cl.msgs
+	cl.msgs = nil
+	return msgs
+}
+
+func (rt *roomTable) enqueue(room_id, client_id, msg string) bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		return false
+	}
+	cl, ok := r.clients[client_id]
+	if !ok {
+		return false
+	}
+	cl.enqueue(msg)
+	return true
+}
+
+// register binds a live WebSocket connection to client_id in room_id and
+// returns the channel its write pump should drain; send() pushes frames
+// onto that channel instead of writing to ws directly.
+func (rt *roomTable) register(room_id, client_id string, ws *websocket.Conn) (chan wsFrame, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r := rt.roomLocked(room_id)
+	cl := r.client(client_id)
+	cl.ws = ws
+	cl.out = make(chan wsFrame, writeChanDepth)
+	return cl.out, nil
+}
+
+func (rt *roomTable) deregister(room_id, client_id string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		return
+	}
+	if cl, ok := r.clients[client_id]; ok {
+		cl.ws = nil
+		if cl.out != nil {
+			close(cl.out)
+			cl.out = nil
+		}
+	}
+}
+
+// disconnect forcibly closes client_id's live connection. It's used by the
+// disconnect-on-overflow queue policy to shed a consumer that can't keep
+// up, rather than letting its queued messages grow without bound.
+func (rt *roomTable) disconnect(room_id, client_id string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		return
+	}
+	cl, ok := r.clients[client_id]
+	if !ok || cl.ws == nil {
+		return
+	}
+	cl.ws.Close()
+}
+
+// dropOldest discards the single oldest message queued for client_id. It's
+// used by the drop-oldest queue policy to bound memory use when a peer
+// isn't draining its queue fast enough.
+func (rt *roomTable) dropOldest(room_id, client_id string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		return
+	}
+	cl, ok := r.clients[client_id]
+	if !ok || len(cl.msgs) == 0 {
+		return
+	}
+	cl.msgs = cl.msgs[1:]
+}
+
+// send routes msg from from_client_id to to_client_id's live WebSocket
+// connection. When to_client_id is empty, msg is broadcast to every other
+// occupant of the room instead of a single peer, which is what lets rooms
+// grow beyond two participants. It returns RESPONSE_UNKNOWN_ROOM/
+// RESPONSE_UNKNOWN_CLIENT when the room or a specific target isn't known
+// locally, which a clustered RoomStore can use to decide whether to fall
+// back to publishing on the shared bus.
+func (rt *roomTable) send(room_id, from_client_id, to_client_id, msg string) error {
+	return rt.deliver(room_id, from_client_id, to_client_id, wsFrame{payload: []byte(msg)})
+}
+
+// sendBinary is the binary-encoding counterpart of send, used for frames
+// received from a client that negotiated encoding "binary" at register
+// time. Unlike send, a clustered RoomStore need not relay these across the
+// shared bus; binary delivery only targets peers registered locally.
+func (rt *roomTable) sendBinary(room_id, from_client_id, to_client_id string, payload []byte) error {
+	return rt.deliver(room_id, from_client_id, to_client_id, wsFrame{binary: true, payload: payload})
+}
+
+func (rt *roomTable) deliver(room_id, from_client_id, to_client_id string, frame wsFrame) error {
+	rt.mu.Lock()
+	r, ok := rt.rooms[room_id]
+	if !ok {
+		rt.mu.Unlock()
+		return errors.New(RESPONSE_UNKNOWN_ROOM)
+	}
+
+	var targets []*client
+	if to_client_id != "" {
+		cl, ok := r.clients[to_client_id]
+		if !ok {
+			rt.mu.Unlock()
+			return errors.New(RESPONSE_UNKNOWN_CLIENT)
+		}
+		targets = []*client{cl}
+	} else {
+		for id, cl := range r.clients {
+			if id != from_client_id {
+				targets = append(targets, cl)
+			}
+		}
+	}
+	rt.mu.Unlock()
+
+	delivered := false
+	for _, cl := range targets {
+		if cl.out == nil {
+			continue
+		}
+		select {
+		case cl.out <- frame:
+			delivered = true
+		default:
+			// The write pump isn't keeping up; drop the frame rather than
+			// block the caller, which may be running on another client's
+			// own read loop.
+		}
+	}
+	if !delivered {
+		return errors.New(RESPONSE_UNKNOWN_CLIENT)
+	}
+	return nil
+}
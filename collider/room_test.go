@@ -0,0 +1,142 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRoomTableAddClientTracksInitiatorAndPeers(t *testing.T) {
+	rt := newRoomTable(time.Minute, "")
+
+	if peers := rt.addClient("room1", "alice"); len(peers) != 0 {
+		t.Fatalf("first join returned peers %v, want none", peers)
+	}
+	if peers := rt.addClient("room1", "bob"); len(peers) != 1 || peers[0] != "alice" {
+		t.Fatalf("second join returned peers %v, want [alice]", peers)
+	}
+	if peers := rt.addClient("room1", "carol"); len(peers) != 2 {
+		t.Fatalf("third join returned peers %v, want 2 peers", peers)
+	}
+	if rt.occupancy("room1") != 3 {
+		t.Fatalf("occupancy = %d, want 3", rt.occupancy("room1"))
+	}
+
+	r := rt.room("room1")
+	if !r.clients["alice"].is_initiator {
+		t.Fatal("first joiner should be recorded as initiator")
+	}
+	if r.clients["bob"].is_initiator || r.clients["carol"].is_initiator {
+		t.Fatal("later joiners should not be recorded as initiator")
+	}
+}
+
+func TestRoomTableRemoveClientPromotesNewInitiator(t *testing.T) {
+	rt := newRoomTable(time.Minute, "")
+	rt.addClient("room1", "alice")
+	rt.addClient("room1", "bob")
+
+	rt.removeClient("room1", "alice")
+
+	r := rt.room("room1")
+	if !r.clients["bob"].is_initiator {
+		t.Fatal("removing the initiator should promote a survivor")
+	}
+	if rt.occupancy("room1") != 1 {
+		t.Fatalf("occupancy = %d, want 1", rt.occupancy("room1"))
+	}
+}
+
+func TestRoomTableRemoveLastClientDropsRoom(t *testing.T) {
+	rt := newRoomTable(time.Minute, "")
+	rt.addClient("room1", "alice")
+
+	rt.removeClient("room1", "alice")
+
+	if rt.roomExists("room1") {
+		t.Fatal("room should be gone once its last client leaves")
+	}
+}
+
+func TestRoomTableDeliverBroadcastsToEveryOtherOccupant(t *testing.T) {
+	rt := newRoomTable(time.Minute, "")
+	rt.addClient("room1", "alice")
+	rt.addClient("room1", "bob")
+	rt.addClient("room1", "carol")
+	outs := map[string]chan wsFrame{
+		"alice": mustRegister(t, rt, "alice"),
+		"bob":   mustRegister(t, rt, "bob"),
+		"carol": mustRegister(t, rt, "carol"),
+	}
+
+	if err := rt.send("room1", "alice", "", "hello"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	select {
+	case <-outs["alice"]:
+		t.Fatal("broadcast should not be delivered back to its sender")
+	default:
+	}
+	for _, id := range []string{"bob", "carol"} {
+		select {
+		case frame := <-outs[id]:
+			if string(frame.payload) != "hello" {
+				t.Fatalf("%s received %q, want %q", id, frame.payload, "hello")
+			}
+		default:
+			t.Fatalf("%s did not receive the broadcast", id)
+		}
+	}
+}
+
+func TestRoomTableDeliverToUnknownClientOrRoom(t *testing.T) {
+	rt := newRoomTable(time.Minute, "")
+	rt.addClient("room1", "alice")
+	mustRegister(t, rt, "alice")
+
+	if err := rt.send("room1", "alice", "nobody", "hi"); err == nil {
+		t.Fatal("send to an unknown client should return an error")
+	}
+	if err := rt.send("no-such-room", "alice", "", "hi"); err == nil {
+		t.Fatal("send to an unknown room should return an error")
+	}
+}
+
+func TestRoomTableQueueLifecycle(t *testing.T) {
+	rt := newRoomTable(time.Minute, "")
+	rt.addClient("room1", "alice")
+
+	if ok := rt.enqueue("room1", "alice", "offer"); !ok {
+		t.Fatal("enqueue for a known client should succeed")
+	}
+	rt.enqueue("room1", "alice", "candidate")
+	if depth := rt.queueDepth("room1", "alice"); depth != 2 {
+		t.Fatalf("queueDepth = %d, want 2", depth)
+	}
+
+	rt.dropOldest("room1", "alice")
+	msgs := rt.drainMessages("room1", "alice")
+	got := append([]string{}, msgs...)
+	sort.Strings(got)
+	if len(got) != 1 || got[0] != "candidate" {
+		t.Fatalf("drainMessages = %v, want [candidate]", got)
+	}
+	if depth := rt.queueDepth("room1", "alice"); depth != 0 {
+		t.Fatalf("queueDepth after drain = %d, want 0", depth)
+	}
+}
+
+func mustRegister(t *testing.T, rt *roomTable, client_id string) chan wsFrame {
+	t.Helper()
+	out, err := rt.register("room1", client_id, nil)
+	if err != nil {
+		t.Fatalf("register(%s): %v", client_id, err)
+	}
+	return out
+}
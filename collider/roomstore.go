@@ -0,0 +1,523 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"golang.org/x/net/websocket"
+)
+
+// RoomStore abstracts where room/client/message state lives. The default
+// in-memory implementation (*roomTable) keeps everything local to one
+// process, which is why today the two peers of a call must land on the
+// same collider instance. A clustered implementation (e.g. natsRoomStore)
+// keeps membership and queued messages in a store shared by every
+// instance, so a load balancer can freely spread clients across boxes.
+type RoomStore interface {
+	// occupancy reports how many clients currently hold room_id.
+	occupancy(room_id string) int
+	roomExists(room_id string) bool
+	clientExists(room_id, client_id string) bool
+
+	// addClient enrolls client_id in room_id and returns the ids of peers
+	// already present.
+	addClient(room_id, client_id string) (peers []string)
+	removeClient(room_id, client_id string)
+
+	// drainMessages returns and clears client_id's queued messages.
+	drainMessages(room_id, client_id string) []string
+	enqueue(room_id, client_id, msg string) bool
+	queueDepth(room_id, client_id string) int
+
+	// dropOldest and disconnect implement the two queue-overflow policies:
+	// shed the oldest queued message, or shed the slow consumer itself.
+	dropOldest(room_id, client_id string)
+	disconnect(room_id, client_id string)
+
+	// register/deregister bind or release the local WebSocket connection
+	// carrying client_id's traffic. register returns the channel that
+	// client_id's write pump should drain.
+	register(room_id, client_id string, ws *websocket.Conn) (chan wsFrame, error)
+	deregister(room_id, client_id string)
+
+	// send routes msg from from_client_id to to_client_id, or broadcasts
+	// it to every other occupant of the room when to_client_id is empty,
+	// routing across the cluster if the implementation supports it.
+	send(room_id, from_client_id, to_client_id, msg string) error
+	// sendBinary is the binary-encoding counterpart of send, delivered
+	// only to peers registered locally.
+	sendBinary(room_id, from_client_id, to_client_id string, payload []byte) error
+}
+
+// roomBusMsg is published on the shared bus so any collider subscribed to
+// a room_id can relay a live send to the client it holds a WebSocket for,
+// or act on a control event such as forceDisconnectKind. Room membership
+// and queued messages themselves live in the members/queues JetStream KV
+// buckets (see natsRoomStore), not on the bus.
+type roomBusMsg struct {
+	Kind         string `json:"kind,omitempty"`
+	RoomID       string `json:"room_id"`
+	FromClientID string `json:"from_client_id,omitempty"`
+	ToClientID   string `json:"to_client_id,omitempty"`
+	Msg          string `json:"msg,omitempty"`
+}
+
+// forceDisconnectKind marks a roomBusMsg as a request to close whichever
+// instance holds ToClientID's live connection, instead of a signaling
+// message to relay.
+const forceDisconnectKind = "disconnect"
+
+const (
+	// membersBucket maps room_id -> JSON []string of its occupants'
+	// client IDs, shared by every collider pointed at the same NATS
+	// cluster.
+	membersBucket = "collider_room_members"
+	// queuesBucket maps "<room_id>.<client_id>" -> JSON []string of that
+	// client's queued offer/candidate messages.
+	queuesBucket = "collider_room_queues"
+	// casAttempts bounds how many times a KV read-modify-write is tried in
+	// total (the first try plus retries after losing a race with another
+	// instance updating the same key) before giving up.
+	casAttempts = 20
+)
+
+// natsRoomStore is a RoomStore backed by NATS JetStream: room membership
+// and queued messages live in JetStream key-value buckets shared by every
+// collider instance pointed at the same NATS cluster, so a peer that
+// joins on a different instance than the one holding its room still sees
+// accurate occupancy and its queued messages. Only the live WebSocket
+// connection itself (and its write-pump channel) stays process-local, in
+// `local`; saveMessageFromClient/addClientToRoom read and write the KV
+// buckets for everything that must be visible cluster-wide, and `send`
+// additionally publishes on a per-room subject so the instance actually
+// holding a live peer can relay to it.
+type natsRoomStore struct {
+	local *roomTable
+
+	nc *nats.Conn
+
+	members nats.KeyValue
+	queues  nats.KeyValue
+
+	mu   sync.Mutex
+	subs map[string]*nats.Subscription
+}
+
+// NewNatsRoomStore connects to a NATS cluster at url and returns a
+// RoomStore backed by its JetStream key-value store, creating the
+// membership and queue buckets if this is the first collider instance to
+// start against them. timeoutSec is forwarded to the local *roomTable
+// that still tracks this instance's own live WebSocket connections, same
+// as the timeout NewCollider gives the default in-memory RoomStore.
+func NewNatsRoomStore(url string, timeoutSec int64, roomSrvUrl string) (RoomStore, error) {
+	// NoEcho keeps this instance from receiving its own publishes back on
+	// its own subscription (see register/send below); without it, a
+	// client held locally would be delivered the same broadcast twice.
+	nc, err := nats.Connect(url, nats.NoEcho())
+	if err != nil {
+		return nil, fmt.Errorf("NewNatsRoomStore: connect to %s: %w", url, err)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("NewNatsRoomStore: JetStream: %w", err)
+	}
+	members, err := openKeyValue(js, membersBucket)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("NewNatsRoomStore: %w", err)
+	}
+	queues, err := openKeyValue(js, queuesBucket)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("NewNatsRoomStore: %w", err)
+	}
+	return &natsRoomStore{
+		local:   newRoomTable(time.Second*time.Duration(timeoutSec), roomSrvUrl),
+		nc:      nc,
+		members: members,
+		queues:  queues,
+		subs:    make(map[string]*nats.Subscription),
+	}, nil
+}
+
+func openKeyValue(js nats.JetStreamContext, bucket string) (nats.KeyValue, error) {
+	kv, err := js.KeyValue(bucket)
+	if err == nil {
+		return kv, nil
+	}
+	kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket})
+	if err != nil {
+		return nil, fmt.Errorf("open bucket %s: %w", bucket, err)
+	}
+	return kv, nil
+}
+
+func roomSubject(room_id string) string {
+	return "collider.room." + room_id
+}
+
+func queueKey(room_id, client_id string) string {
+	return room_id + "." + client_id
+}
+
+// isCASConflict reports whether err is a JetStream KV optimistic-
+// concurrency failure -- another instance updated or deleted the key
+// between our Get and Create/Update/Delete -- in which case the caller
+// should re-read and retry instead of treating it as a real error.
+func isCASConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, nats.ErrKeyExists) {
+		return true
+	}
+	return strings.Contains(err.Error(), "wrong last sequence")
+}
+
+// getMembers returns the client IDs occupying room_id and the KV revision
+// their entry was read at (0 if the room has no entry yet).
+func (s *natsRoomStore) getMembers(room_id string) (ids []string, revision uint64, err error) {
+	entry, err := s.members.Get(room_id)
+	if err != nil {
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	if err := json.Unmarshal(entry.Value(), &ids); err != nil {
+		return nil, 0, err
+	}
+	return ids, entry.Revision(), nil
+}
+
+func (s *natsRoomStore) occupancy(room_id string) int {
+	ids, _, err := s.getMembers(room_id)
+	if err != nil {
+		fmt.Printf("natsRoomStore.occupancy: %s: %v\n", room_id, err)
+		return 0
+	}
+	return len(ids)
+}
+
+func (s *natsRoomStore) roomExists(room_id string) bool {
+	ids, _, err := s.getMembers(room_id)
+	return err == nil && len(ids) > 0
+}
+
+func (s *natsRoomStore) clientExists(room_id, client_id string) bool {
+	ids, _, err := s.getMembers(room_id)
+	if err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if id == client_id {
+			return true
+		}
+	}
+	return false
+}
+
+// addClient enrolls client_id in room_id's shared membership entry,
+// retrying the compare-and-swap against JetStream if another instance
+// updates the same room concurrently, and returns the peers that were
+// already present.
+func (s *natsRoomStore) addClient(room_id, client_id string) (peers []string) {
+	for attempt := 0; attempt < casAttempts; attempt++ {
+		ids, revision, err := s.getMembers(room_id)
+		if err != nil {
+			fmt.Printf("natsRoomStore.addClient: %s: %v\n", room_id, err)
+			return nil
+		}
+		peers = append([]string(nil), ids...)
+		updated := append(append([]string(nil), ids...), client_id)
+		data, err := json.Marshal(updated)
+		if err != nil {
+			fmt.Printf("natsRoomStore.addClient: %s: %v\n", room_id, err)
+			return peers
+		}
+		if _, err := s.members.Update(room_id, data, revision); err != nil {
+			if isCASConflict(err) {
+				continue
+			}
+			fmt.Printf("natsRoomStore.addClient: %s: %v\n", room_id, err)
+			return peers
+		}
+		return peers
+	}
+	fmt.Printf("natsRoomStore.addClient: %s: exceeded %d CAS attempts\n", room_id, casAttempts)
+	return peers
+}
+
+// removeClient retracts client_id from room_id's shared membership entry,
+// deleting the entry (and this instance's relay subscription) once the
+// room is empty, and purges client_id's queued messages. It also releases
+// any local WebSocket connection removeClient may be holding for
+// client_id, same as roomTable.removeClient.
+func (s *natsRoomStore) removeClient(room_id, client_id string) {
+	for attempt := 0; attempt < casAttempts; attempt++ {
+		ids, revision, err := s.getMembers(room_id)
+		if err != nil {
+			fmt.Printf("natsRoomStore.removeClient: %s: %v\n", room_id, err)
+			return
+		}
+		if revision == 0 {
+			break
+		}
+		remaining := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if id != client_id {
+				remaining = append(remaining, id)
+			}
+		}
+		if len(remaining) == len(ids) {
+			break // client_id wasn't a member
+		}
+		if len(remaining) == 0 {
+			if err := s.members.Delete(room_id, nats.LastRevision(revision)); err != nil {
+				if isCASConflict(err) {
+					continue
+				}
+				fmt.Printf("natsRoomStore.removeClient: %s: %v\n", room_id, err)
+				return
+			}
+			s.unsubscribe(room_id)
+			break
+		}
+		data, err := json.Marshal(remaining)
+		if err != nil {
+			fmt.Printf("natsRoomStore.removeClient: %s: %v\n", room_id, err)
+			return
+		}
+		if _, err := s.members.Update(room_id, data, revision); err != nil {
+			if isCASConflict(err) {
+				continue
+			}
+			fmt.Printf("natsRoomStore.removeClient: %s: %v\n", room_id, err)
+			return
+		}
+		break
+	}
+
+	if err := s.queues.Delete(queueKey(room_id, client_id)); err != nil && !errors.Is(err, nats.ErrKeyNotFound) {
+		fmt.Printf("natsRoomStore.removeClient: purge queue for %s/%s: %v\n", room_id, client_id, err)
+	}
+	s.local.removeClient(room_id, client_id)
+}
+
+func (s *natsRoomStore) unsubscribe(room_id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sub, ok := s.subs[room_id]; ok {
+		sub.Unsubscribe()
+		delete(s.subs, room_id)
+	}
+}
+
+// getQueue returns client_id's queued messages and the KV revision they
+// were read at (0 if it has no queue entry yet).
+func (s *natsRoomStore) getQueue(room_id, client_id string) (msgs []string, revision uint64, err error) {
+	entry, err := s.queues.Get(queueKey(room_id, client_id))
+	if err != nil {
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	if err := json.Unmarshal(entry.Value(), &msgs); err != nil {
+		return nil, 0, err
+	}
+	return msgs, entry.Revision(), nil
+}
+
+func (s *natsRoomStore) queueDepth(room_id, client_id string) int {
+	msgs, _, err := s.getQueue(room_id, client_id)
+	if err != nil {
+		fmt.Printf("natsRoomStore.queueDepth: %s/%s: %v\n", room_id, client_id, err)
+		return 0
+	}
+	return len(msgs)
+}
+
+func (s *natsRoomStore) drainMessages(room_id, client_id string) []string {
+	key := queueKey(room_id, client_id)
+	for attempt := 0; attempt < casAttempts; attempt++ {
+		msgs, revision, err := s.getQueue(room_id, client_id)
+		if err != nil {
+			fmt.Printf("natsRoomStore.drainMessages: %s/%s: %v\n", room_id, client_id, err)
+			return nil
+		}
+		if revision == 0 {
+			return nil
+		}
+		if err := s.queues.Delete(key, nats.LastRevision(revision)); err != nil {
+			if isCASConflict(err) {
+				continue
+			}
+			fmt.Printf("natsRoomStore.drainMessages: %s/%s: %v\n", room_id, client_id, err)
+			return nil
+		}
+		return msgs
+	}
+	fmt.Printf("natsRoomStore.drainMessages: %s/%s: exceeded %d CAS attempts\n", room_id, client_id, casAttempts)
+	return nil
+}
+
+func (s *natsRoomStore) enqueue(room_id, client_id, msg string) bool {
+	key := queueKey(room_id, client_id)
+	for attempt := 0; attempt < casAttempts; attempt++ {
+		msgs, revision, err := s.getQueue(room_id, client_id)
+		if err != nil {
+			fmt.Printf("natsRoomStore.enqueue: %s/%s: %v\n", room_id, client_id, err)
+			return false
+		}
+		data, err := json.Marshal(append(msgs, msg))
+		if err != nil {
+			fmt.Printf("natsRoomStore.enqueue: %s/%s: %v\n", room_id, client_id, err)
+			return false
+		}
+		if _, err := s.queues.Update(key, data, revision); err != nil {
+			if isCASConflict(err) {
+				continue
+			}
+			fmt.Printf("natsRoomStore.enqueue: %s/%s: %v\n", room_id, client_id, err)
+			return false
+		}
+		return true
+	}
+	fmt.Printf("natsRoomStore.enqueue: %s/%s: exceeded %d CAS attempts\n", room_id, client_id, casAttempts)
+	return false
+}
+
+func (s *natsRoomStore) dropOldest(room_id, client_id string) {
+	key := queueKey(room_id, client_id)
+	for attempt := 0; attempt < casAttempts; attempt++ {
+		msgs, revision, err := s.getQueue(room_id, client_id)
+		if err != nil {
+			fmt.Printf("natsRoomStore.dropOldest: %s/%s: %v\n", room_id, client_id, err)
+			return
+		}
+		if revision == 0 || len(msgs) == 0 {
+			return
+		}
+		data, err := json.Marshal(msgs[1:])
+		if err != nil {
+			fmt.Printf("natsRoomStore.dropOldest: %s/%s: %v\n", room_id, client_id, err)
+			return
+		}
+		if _, err := s.queues.Update(key, data, revision); err != nil {
+			if isCASConflict(err) {
+				continue
+			}
+			fmt.Printf("natsRoomStore.dropOldest: %s/%s: %v\n", room_id, client_id, err)
+			return
+		}
+		return
+	}
+	fmt.Printf("natsRoomStore.dropOldest: %s/%s: exceeded %d CAS attempts\n", room_id, client_id, casAttempts)
+}
+
+// disconnect closes client_id's live connection if it's held locally, and
+// also publishes a forceDisconnectKind control message so whichever other
+// instance actually holds it (if any) closes it too.
+func (s *natsRoomStore) disconnect(room_id, client_id string) {
+	s.local.disconnect(room_id, client_id)
+
+	data, err := json.Marshal(roomBusMsg{Kind: forceDisconnectKind, RoomID: room_id, ToClientID: client_id})
+	if err != nil {
+		fmt.Printf("natsRoomStore.disconnect: %s/%s: %v\n", room_id, client_id, err)
+		return
+	}
+	if err := s.nc.Publish(roomSubject(room_id), data); err != nil {
+		fmt.Printf("natsRoomStore.disconnect: %s/%s: publish: %v\n", room_id, client_id, err)
+	}
+}
+
+// sendBinary only relays to peers registered on this instance: binary
+// frames aren't published on the NATS bus, unlike the text path below.
+func (s *natsRoomStore) sendBinary(room_id, from_client_id, to_client_id string, payload []byte) error {
+	return s.local.sendBinary(room_id, from_client_id, to_client_id, payload)
+}
+
+// register binds ws locally and, the first time this process sees the
+// room, subscribes to its subject so peers routed to other colliders can
+// reach clients registered here, and so a forceDisconnectKind control
+// message aimed at a client held here takes effect.
+func (s *natsRoomStore) register(room_id, client_id string, ws *websocket.Conn) (chan wsFrame, error) {
+	out, err := s.local.register(room_id, client_id, ws)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	_, subscribed := s.subs[room_id]
+	s.mu.Unlock()
+	if subscribed {
+		return out, nil
+	}
+
+	sub, err := s.nc.Subscribe(roomSubject(room_id), func(natsMsg *nats.Msg) {
+		var m roomBusMsg
+		if err := json.Unmarshal(natsMsg.Data, &m); err != nil {
+			return
+		}
+		if m.Kind == forceDisconnectKind {
+			s.local.disconnect(m.RoomID, m.ToClientID)
+			return
+		}
+		// Only clients registered on this instance can actually be
+		// delivered to; other colliders subscribed to the same subject
+		// silently ignore messages meant for peers they don't hold.
+		s.local.send(m.RoomID, m.FromClientID, m.ToClientID, m.Msg)
+	})
+	if err != nil {
+		return out, err
+	}
+	s.mu.Lock()
+	s.subs[room_id] = sub
+	s.mu.Unlock()
+	return out, nil
+}
+
+func (s *natsRoomStore) deregister(room_id, client_id string) {
+	s.local.deregister(room_id, client_id)
+}
+
+// send delivers locally first, then always also publishes to the room's
+// subject so whichever collider holds the remaining peers relays it too.
+// It always publishes, rather than only when local delivery didn't reach
+// everyone, because with NoEcho set on Connect this instance never
+// receives its own publish back -- so there's no double delivery to
+// publish guard against, and skipping the publish whenever local delivery
+// happened to fully succeed would miss remote peers in a >2-party room.
+//
+// localErr is expected whenever the target is registered on a different
+// collider instance (s.local has never heard of it) -- that's the normal
+// cross-instance case this store exists to support, not a failure, so it
+// only gets surfaced when the publish that's supposed to reach that other
+// instance fails too.
+func (s *natsRoomStore) send(room_id, from_client_id, to_client_id, msg string) error {
+	localErr := s.local.send(room_id, from_client_id, to_client_id, msg)
+
+	data, err := json.Marshal(roomBusMsg{RoomID: room_id, FromClientID: from_client_id, ToClientID: to_client_id, Msg: msg})
+	if err != nil {
+		return err
+	}
+	if err := s.nc.Publish(roomSubject(room_id), data); err != nil {
+		if localErr != nil {
+			return localErr
+		}
+		return errors.New("natsRoomStore.send: publish: " + err.Error())
+	}
+	return nil
+}
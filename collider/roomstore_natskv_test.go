@@ -0,0 +1,219 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// fakeKV is a minimal in-process stand-in for a JetStream nats.KeyValue
+// bucket, just enough to drive natsRoomStore's CAS-retry loops (Get,
+// Update, Delete) without a real NATS server. conflictOnce lets a test
+// force a given number of CAS failures on a key before the retry loop's
+// next attempt is allowed through, exercising the retry path itself.
+type fakeKV struct {
+	entries      map[string]fakeKVEntry
+	seq          uint64
+	conflictOnce map[string]int
+}
+
+type fakeKVEntry struct {
+	value    []byte
+	revision uint64
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{entries: make(map[string]fakeKVEntry), conflictOnce: make(map[string]int)}
+}
+
+// forceConflicts makes the next n Update/Delete calls against key fail
+// with a CAS conflict before letting the call through.
+func (kv *fakeKV) forceConflicts(key string, n int) {
+	kv.conflictOnce[key] = n
+}
+
+func (kv *fakeKV) takeConflict(key string) bool {
+	if kv.conflictOnce[key] <= 0 {
+		return false
+	}
+	kv.conflictOnce[key]--
+	return true
+}
+
+func (kv *fakeKV) Get(key string) (nats.KeyValueEntry, error) {
+	e, ok := kv.entries[key]
+	if !ok {
+		return nil, nats.ErrKeyNotFound
+	}
+	return &fakeKVEntryHandle{key: key, entry: e}, nil
+}
+
+func (kv *fakeKV) Update(key string, value []byte, last uint64) (uint64, error) {
+	if kv.takeConflict(key) {
+		return 0, errors.New("nats: wrong last sequence: 0")
+	}
+	e, ok := kv.entries[key]
+	var cur uint64
+	if ok {
+		cur = e.revision
+	}
+	if cur != last {
+		return 0, nats.ErrKeyExists
+	}
+	kv.seq++
+	kv.entries[key] = fakeKVEntry{value: value, revision: kv.seq}
+	return kv.seq, nil
+}
+
+func (kv *fakeKV) Delete(key string, opts ...nats.DeleteOpt) error {
+	if kv.takeConflict(key) {
+		return errors.New("nats: wrong last sequence: 0")
+	}
+	delete(kv.entries, key)
+	return nil
+}
+
+func (kv *fakeKV) GetRevision(key string, revision uint64) (nats.KeyValueEntry, error) {
+	panic("not implemented")
+}
+func (kv *fakeKV) Put(key string, value []byte) (uint64, error)       { panic("not implemented") }
+func (kv *fakeKV) PutString(key string, value string) (uint64, error) { panic("not implemented") }
+func (kv *fakeKV) Create(key string, value []byte) (uint64, error)    { panic("not implemented") }
+func (kv *fakeKV) Purge(key string, opts ...nats.DeleteOpt) error     { panic("not implemented") }
+func (kv *fakeKV) Watch(keys string, opts ...nats.WatchOpt) (nats.KeyWatcher, error) {
+	panic("not implemented")
+}
+func (kv *fakeKV) WatchAll(opts ...nats.WatchOpt) (nats.KeyWatcher, error) {
+	panic("not implemented")
+}
+func (kv *fakeKV) WatchFiltered(keys []string, opts ...nats.WatchOpt) (nats.KeyWatcher, error) {
+	panic("not implemented")
+}
+func (kv *fakeKV) Keys(opts ...nats.WatchOpt) ([]string, error) { panic("not implemented") }
+func (kv *fakeKV) ListKeys(opts ...nats.WatchOpt) (nats.KeyLister, error) {
+	panic("not implemented")
+}
+func (kv *fakeKV) History(key string, opts ...nats.WatchOpt) ([]nats.KeyValueEntry, error) {
+	panic("not implemented")
+}
+func (kv *fakeKV) Bucket() string                           { return "fake" }
+func (kv *fakeKV) PurgeDeletes(opts ...nats.PurgeOpt) error { panic("not implemented") }
+func (kv *fakeKV) Status() (nats.KeyValueStatus, error)     { panic("not implemented") }
+
+type fakeKVEntryHandle struct {
+	key   string
+	entry fakeKVEntry
+}
+
+func (e *fakeKVEntryHandle) Bucket() string             { return "fake" }
+func (e *fakeKVEntryHandle) Key() string                { return e.key }
+func (e *fakeKVEntryHandle) Value() []byte              { return e.entry.value }
+func (e *fakeKVEntryHandle) Revision() uint64           { return e.entry.revision }
+func (e *fakeKVEntryHandle) Created() time.Time         { return time.Time{} }
+func (e *fakeKVEntryHandle) Delta() uint64              { return 0 }
+func (e *fakeKVEntryHandle) Operation() nats.KeyValueOp { return nats.KeyValuePut }
+
+func newTestNatsRoomStore() *natsRoomStore {
+	return &natsRoomStore{
+		local:   newRoomTable(time.Minute, ""),
+		members: newFakeKV(),
+		queues:  newFakeKV(),
+		subs:    make(map[string]*nats.Subscription),
+	}
+}
+
+func TestNatsRoomStoreAddClientTracksSharedMembership(t *testing.T) {
+	s := newTestNatsRoomStore()
+
+	if peers := s.addClient("room1", "alice"); len(peers) != 0 {
+		t.Fatalf("first addClient returned peers %v, want none", peers)
+	}
+	if peers := s.addClient("room1", "bob"); len(peers) != 1 || peers[0] != "alice" {
+		t.Fatalf("second addClient returned peers %v, want [alice]", peers)
+	}
+	if s.occupancy("room1") != 2 {
+		t.Fatalf("occupancy = %d, want 2", s.occupancy("room1"))
+	}
+	if !s.clientExists("room1", "bob") {
+		t.Fatal("clientExists(bob) = false, want true")
+	}
+}
+
+func TestNatsRoomStoreAddClientRetriesOnCASConflict(t *testing.T) {
+	s := newTestNatsRoomStore()
+	s.addClient("room1", "alice")
+
+	kv := s.members.(*fakeKV)
+	kv.forceConflicts("room1", 2)
+
+	peers := s.addClient("room1", "bob")
+	if len(peers) != 1 || peers[0] != "alice" {
+		t.Fatalf("addClient after CAS conflicts returned peers %v, want [alice]", peers)
+	}
+	if !s.clientExists("room1", "bob") {
+		t.Fatal("bob was not recorded as a member after the retried addClient")
+	}
+}
+
+func TestNatsRoomStoreRemoveClientDeletesEmptyRoom(t *testing.T) {
+	s := newTestNatsRoomStore()
+	s.addClient("room1", "alice")
+	s.addClient("room1", "bob")
+
+	s.removeClient("room1", "alice")
+	if s.occupancy("room1") != 1 || s.clientExists("room1", "alice") {
+		t.Fatal("removeClient did not retract alice from shared membership")
+	}
+
+	s.removeClient("room1", "bob")
+	if s.roomExists("room1") {
+		t.Fatal("room should have no shared membership entry once its last client leaves")
+	}
+}
+
+func TestNatsRoomStoreQueueLifecycle(t *testing.T) {
+	s := newTestNatsRoomStore()
+	s.addClient("room1", "alice")
+
+	if ok := s.enqueue("room1", "alice", "offer"); !ok {
+		t.Fatal("enqueue should succeed")
+	}
+	s.enqueue("room1", "alice", "candidate")
+	if depth := s.queueDepth("room1", "alice"); depth != 2 {
+		t.Fatalf("queueDepth = %d, want 2", depth)
+	}
+
+	s.dropOldest("room1", "alice")
+	msgs := s.drainMessages("room1", "alice")
+	got := append([]string{}, msgs...)
+	sort.Strings(got)
+	if len(got) != 1 || got[0] != "candidate" {
+		t.Fatalf("drainMessages = %v, want [candidate]", got)
+	}
+	if depth := s.queueDepth("room1", "alice"); depth != 0 {
+		t.Fatalf("queueDepth after drain = %d, want 0", depth)
+	}
+}
+
+func TestNatsRoomStoreEnqueueRetriesOnCASConflict(t *testing.T) {
+	s := newTestNatsRoomStore()
+	s.addClient("room1", "alice")
+
+	kv := s.queues.(*fakeKV)
+	kv.forceConflicts(queueKey("room1", "alice"), casAttempts-1)
+
+	if ok := s.enqueue("room1", "alice", "offer"); !ok {
+		t.Fatal("enqueue should eventually succeed within casAttempts retries")
+	}
+	if depth := s.queueDepth("room1", "alice"); depth != 1 {
+		t.Fatalf("queueDepth = %d, want 1", depth)
+	}
+}
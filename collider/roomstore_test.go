@@ -0,0 +1,40 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestIsCASConflict(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"key exists", nats.ErrKeyExists, true},
+		{"wrong last sequence", errors.New("nats: wrong last sequence: 3"), true},
+		{"unrelated error", errors.New("nats: timeout"), false},
+	}
+	for _, c := range cases {
+		if got := isCASConflict(c.err); got != c.want {
+			t.Errorf("isCASConflict(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestQueueKeyAndRoomSubjectAreDistinct(t *testing.T) {
+	if roomSubject("room1") == roomSubject("room2") {
+		t.Fatal("roomSubject collided for distinct rooms")
+	}
+	if queueKey("room1", "alice") == queueKey("room1", "bob") {
+		t.Fatal("queueKey did not vary with client_id")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// backendEvent is the JSON payload posted to -backend-url for every room
+// lifecycle event, mirroring the "backend server" pattern where collider
+// is a dumb signaling pipe and the application server owns authorization,
+// recording and billing decisions.
+type backendEvent struct {
+	Event     string `json:"event"`
+	RoomID    string `json:"room_id"`
+	ClientID  string `json:"client_id"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+const (
+	backendEventJoin       = "join"
+	backendEventLeave      = "leave"
+	backendEventTimeout    = "timeout"
+	backendEventRoomEmpty  = "room_empty"
+	webhookDispatchWorkers = 4
+	webhookMaxRetries      = 3
+)
+
+// BackendNotifier posts room lifecycle events to an application server.
+// Joins are notified synchronously so a non-2xx response can veto the
+// join; every other event is dispatched through a small worker pool so a
+// slow backend never blocks the WebSocket read loop.
+type BackendNotifier struct {
+	url    string
+	secret []byte
+	client *http.Client
+	jobs   chan backendEvent
+}
+
+// NewBackendNotifier starts the dispatcher pool and returns a
+// BackendNotifier that posts signed events to url.
+func NewBackendNotifier(url, secret string) *BackendNotifier {
+	n := &BackendNotifier{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: 5 * time.Second},
+		jobs:   make(chan backendEvent, 256),
+	}
+	for i := 0; i < webhookDispatchWorkers; i++ {
+		go n.dispatchLoop()
+	}
+	return n
+}
+
+func (n *BackendNotifier) dispatchLoop() {
+	for ev := range n.jobs {
+		if err := n.post(ev); err != nil {
+			fmt.Printf("BackendNotifier: %s for room %s client %s failed: %v\n", ev.Event, ev.RoomID, ev.ClientID, err)
+		}
+	}
+}
+
+// Notify enqueues event for asynchronous delivery and returns immediately.
+func (n *BackendNotifier) Notify(event, room_id, client_id string) {
+	select {
+	case n.jobs <- backendEvent{Event: event, RoomID: room_id, ClientID: client_id, Timestamp: time.Now().Unix()}:
+	default:
+		fmt.Printf("BackendNotifier: dropping %s event for room %s client %s, dispatcher queue full\n", event, room_id, client_id)
+	}
+}
+
+// NotifyJoin posts the join event synchronously so the caller can veto
+// the join: a non-2xx response (after retries) is surfaced as an error.
+func (n *BackendNotifier) NotifyJoin(room_id, client_id string) error {
+	return n.post(backendEvent{Event: backendEventJoin, RoomID: room_id, ClientID: client_id, Timestamp: time.Now().Unix()})
+}
+
+func (n *BackendNotifier) post(ev backendEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	backoff := 200 * time.Millisecond
+	for attempt := 0; attempt < webhookMaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		req, err := http.NewRequest("POST", n.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Collider-Signature", n.sign(body))
+
+		resp, err := n.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("backend returned %d", resp.StatusCode)
+			continue
+		}
+		// 4xx is a deliberate veto/rejection, not a transient failure.
+		return fmt.Errorf("backend rejected event with status %d", resp.StatusCode)
+	}
+	return lastErr
+}
+
+func (n *BackendNotifier) sign(body []byte) string {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2014 The WebRTC project authors. All Rights Reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package collider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBackendNotifierNotifyJoinSignsTheBody(t *testing.T) {
+	var gotBody []byte
+	var gotSig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSig = r.Header.Get("X-Collider-Signature")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	n := NewBackendNotifier(srv.URL, "s3cret")
+	if err := n.NotifyJoin("room1", "alice"); err != nil {
+		t.Fatalf("NotifyJoin: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(gotBody)
+	want := hex.EncodeToString(mac.Sum(nil))
+	if gotSig != want {
+		t.Fatalf("signature = %q, want %q", gotSig, want)
+	}
+}
+
+func TestBackendNotifierNotifyJoinVetoIsNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	n := NewBackendNotifier(srv.URL, "s3cret")
+	if err := n.NotifyJoin("room1", "alice"); err == nil {
+		t.Fatal("NotifyJoin should surface a 4xx veto as an error")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("backend was called %d times, want 1 (a veto must not be retried)", got)
+	}
+}
+
+func TestBackendNotifierNotifyJoinRetriesOn5xxThenFails(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	n := NewBackendNotifier(srv.URL, "s3cret")
+	if err := n.NotifyJoin("room1", "alice"); err == nil {
+		t.Fatal("NotifyJoin should return an error once retries are exhausted")
+	}
+	if got := atomic.LoadInt32(&calls); got != webhookMaxRetries {
+		t.Fatalf("backend was called %d times, want %d", got, webhookMaxRetries)
+	}
+}
+
+func TestBackendNotifierNotifyJoinSucceedsAfterTransientFailure(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	n := NewBackendNotifier(srv.URL, "s3cret")
+	if err := n.NotifyJoin("room1", "alice"); err != nil {
+		t.Fatalf("NotifyJoin: %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("backend was called %d times, want 2", got)
+	}
+}
@@ -7,13 +7,32 @@ package main
 
 import (
 	"flag"
+	"io/ioutil"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
 	"ngrtc/collider"
 )
 
 var tls = flag.Bool("tls", false, "whether TLS is used")
 var port = flag.Int("port", 8080, "The TCP port that the server listens on")
 var roomSrv = flag.String("room-server", "127.0.0.1", "The origin of the room server")
+var grpcPort = flag.Int("grpc-port", 0, "The TCP port the gRPC signaling frontend listens on, 0 to disable")
+var maxParticipantsPerRoom = flag.Int("max-participants-per-room", 2, "The maximum number of clients allowed to occupy one room")
+var authSecretFile = flag.String("auth-secret", "", "Path to a file holding the HMAC secret required to join a room; empty disables authenticated join")
+var backendUrl = flag.String("backend-url", "", "URL of an application server notified of room lifecycle events (join, leave, timeout, room_empty); empty disables backend notifications")
+var backendSecretFile = flag.String("backend-secret", "", "Path to a file holding the HMAC secret used to sign -backend-url requests")
+var maxQueuedMessages = flag.Int("max-queued-messages", 64, "The maximum number of messages queued for a client awaiting its peer, 0 for unlimited")
+var disconnectOnQueueOverflow = flag.Bool("disconnect-on-queue-overflow", false, "Disconnect a client whose queue overflows instead of dropping its oldest queued message")
+var roomStore = flag.String("room-store", "memory", "Which RoomStore backend to use: \"memory\" (default, room state local to this process) or \"nats\" (shared across instances via a NATS cluster; see -nats-url)")
+var natsUrl = flag.String("nats-url", "nats://127.0.0.1:4222", "NATS server URL used when -room-store=nats")
+
+// natsRoomStoreTimeoutSec matches registerTimeoutSec, the timeout the
+// default in-memory RoomStore is given in collider.NewCollider.
+const natsRoomStoreTimeoutSec = 60 * 10
 
 func main() {
 	flag.Parse()
@@ -21,5 +40,63 @@ func main() {
 	log.Printf("Starting collider: tls = %t, port = %d, room-server=%s", *tls, *port, *roomSrv)
 
 	c := collider.NewCollider(*roomSrv)
-	c.Run(*port, *tls)
+
+	opts := []collider.Option{
+		collider.WithMaxParticipantsPerRoom(*maxParticipantsPerRoom),
+		collider.WithMaxQueuedMessages(*maxQueuedMessages),
+	}
+	if *grpcPort != 0 {
+		opts = append(opts, collider.WithGRPCPort(*grpcPort))
+		log.Printf("Starting collider gRPC frontend: port = %d", *grpcPort)
+	}
+	if *disconnectOnQueueOverflow {
+		opts = append(opts, collider.WithDisconnectOnQueueOverflow())
+	}
+	switch *roomStore {
+	case "memory":
+		// Default: collider.NewCollider already set this up.
+	case "nats":
+		rs, err := collider.NewNatsRoomStore(*natsUrl, natsRoomStoreTimeoutSec, *roomSrv)
+		if err != nil {
+			log.Fatal("NewNatsRoomStore: " + err.Error())
+		}
+		opts = append(opts, collider.WithRoomStore(rs))
+		log.Printf("Using NATS-backed RoomStore: url = %s", *natsUrl)
+	default:
+		log.Fatalf("Unknown -room-store %q", *roomStore)
+	}
+	if *authSecretFile != "" {
+		auth := collider.NewAuthManager(readAuthSecret(*authSecretFile))
+		opts = append(opts, collider.WithAuth(auth))
+
+		// Allow rotating the secret without a restart: SIGHUP re-reads the
+		// secret file and swaps it into the running AuthManager.
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		go func() {
+			for range sighup {
+				auth.SetSecret(readAuthSecret(*authSecretFile))
+				log.Printf("Reloaded auth secret from %s", *authSecretFile)
+			}
+		}()
+	}
+
+	if *backendUrl != "" {
+		secret := ""
+		if *backendSecretFile != "" {
+			secret = readAuthSecret(*backendSecretFile)
+		}
+		opts = append(opts, collider.WithBackendNotifier(collider.NewBackendNotifier(*backendUrl, secret)))
+		log.Printf("Notifying backend of room events: url = %s", *backendUrl)
+	}
+
+	c.Run(*port, *tls, opts...)
+}
+
+func readAuthSecret(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal("readAuthSecret: " + err.Error())
+	}
+	return strings.TrimSpace(string(data))
 }